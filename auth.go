@@ -0,0 +1,119 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Authenticator adds credentials to a request. It is applied to every attempt, after the request
+// has been logged, so credentials never appear in logs, traces or hook events.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// ChallengeAuthenticator is an Authenticator that can answer a 401 response, e.g. by refreshing
+// an expired token. When Challenge returns true the attempt is sent once more, and only the outcome
+// of that second request counts for retries, circuit breakers and metrics.
+type ChallengeAuthenticator interface {
+	Authenticator
+	Challenge(resp *Response) bool
+}
+
+// BasicAuth authenticates with HTTP Basic credentials
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// Authenticate implements Authenticator
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// BearerAuth authenticates with a static bearer token
+type BearerAuth struct {
+	Token string
+}
+
+// Authenticate implements Authenticator
+func (a BearerAuth) Authenticate(req *http.Request) error {
+	if a.Token == "" {
+		return errors.New("bearer token is empty")
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// APIKeyAuth authenticates with an API key sent in a header or, when QueryParam is set, in the query string
+type APIKeyAuth struct {
+	Key        string
+	Header     string // Header carrying the key, default "X-API-Key"
+	QueryParam string // Query parameter carrying the key instead of a header
+}
+
+// Authenticate implements Authenticator
+func (a APIKeyAuth) Authenticate(req *http.Request) error {
+	if a.Key == "" {
+		return errors.New("api key is empty")
+	}
+
+	if a.QueryParam != "" {
+		q := req.URL.Query()
+		q.Set(a.QueryParam, a.Key)
+		req.URL.RawQuery = q.Encode()
+		return nil
+	}
+
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	req.Header.Set(header, a.Key)
+	return nil
+}
+
+type authenticatorKey struct{}
+
+// WithAuthenticator returns a context whose calls are authenticated with auth instead of the
+// client's Authenticator
+func WithAuthenticator(ctx context.Context, auth Authenticator) context.Context {
+	return context.WithValue(ctx, authenticatorKey{}, auth)
+}
+
+// authenticatorFor returns the authenticator of a call: the one in its context, or the client's
+func authenticatorFor(ctx context.Context) Authenticator {
+	if auth, ok := ctx.Value(authenticatorKey{}).(Authenticator); ok {
+		return auth
+	}
+	return config.Authenticator
+}
+
+// authenticate applies the authenticator of a call to one attempt
+func authenticate(auth Authenticator, req *http.Request) error {
+	if auth == nil {
+		return nil
+	}
+	return auth.Authenticate(req)
+}
+
+// answerChallenge reports whether an attempt rejected with a 401 should be sent once more
+func answerChallenge(auth Authenticator, resp *Response) bool {
+	challenger, ok := auth.(ChallengeAuthenticator)
+	if !ok || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	return challenger.Challenge(resp)
+}
+
+// redactURLError replaces the URL in transport errors with the logged one, which carries no
+// credentials added by an Authenticator
+func redactURLError(err error, loggedURL string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = loggedURL
+	}
+	return err
+}
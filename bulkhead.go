@@ -0,0 +1,236 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AdaptiveAlgorithm selects how an adaptive concurrency limit reacts to latency and errors
+type AdaptiveAlgorithm int
+
+const (
+	// AIMD increases the limit by one per window of successful requests and multiplies it by
+	// BackoffRatio on errors or when latency exceeds LatencyThreshold
+	AIMD AdaptiveAlgorithm = iota
+	// Gradient scales the limit by the ratio of the long-term latency to the current latency,
+	// shrinking it as soon as queueing shows up upstream
+	Gradient
+)
+
+// ErrConcurrencyLimited is returned when an upstream's adaptive concurrency limit is reached
+var ErrConcurrencyLimited = errors.New("concurrency limit reached")
+
+// adaptiveLimiter is a bulkhead whose size adapts to the observed latency and errors of one upstream
+type adaptiveLimiter struct {
+	key string
+	cfg *AdaptiveConcurrencyConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  []chan struct{}
+	longRTT  float64 // Exponentially smoothed latency in seconds, Gradient only
+}
+
+// adaptiveLimiters holds one adaptive limiter per upstream host
+type adaptiveLimiters struct {
+	cfg *AdaptiveConcurrencyConfig
+
+	mu    sync.Mutex
+	hosts map[string]*adaptiveLimiter
+}
+
+var bulkheads *adaptiveLimiters
+
+// newAdaptiveLimiters creates the bulkheads for a configuration, or nil when disabled
+func newAdaptiveLimiters(cfg *AdaptiveConcurrencyConfig) *adaptiveLimiters {
+	if cfg == nil {
+		return nil
+	}
+	return &adaptiveLimiters{
+		cfg:   cfg,
+		hosts: make(map[string]*adaptiveLimiter),
+	}
+}
+
+// get returns the limiter for the URL host, creating it on first use
+func (a *adaptiveLimiters) get(urlStr string) *adaptiveLimiter {
+	if a == nil {
+		return nil
+	}
+
+	host := urlStr
+	if u, err := url.Parse(urlStr); err == nil {
+		host = u.Host
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	l, ok := a.hosts[host]
+	if !ok {
+		l = &adaptiveLimiter{
+			key:   host,
+			cfg:   a.cfg,
+			limit: float64(a.cfg.InitialLimit),
+		}
+		a.hosts[host] = l
+	}
+	return l
+}
+
+// acquire takes a slot, waiting up to MaxWait for one to free up
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if float64(l.inFlight) < math.Floor(l.limit) {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if l.cfg.MaxWait <= 0 {
+		l.mu.Unlock()
+		return fmt.Errorf("%w for %s (%d in flight)", ErrConcurrencyLimited, l.key, l.inFlight)
+	}
+
+	// Queue up; release hands the slot over directly
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.MaxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+		err = fmt.Errorf("%w for %s after waiting %s", ErrConcurrencyLimited, l.key, l.cfg.MaxWait)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// Stop waiting, giving back the slot if it was handed over in the meantime
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, waiter := range l.waiters {
+		if waiter == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return err
+		}
+	}
+	l.inFlight--
+	l.wakeWaiters()
+	return err
+}
+
+// release frees a slot and adapts the limit to the observed latency and outcome
+func (l *adaptiveLimiter) release(latency time.Duration, dropped bool) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous := math.Floor(l.limit)
+	switch l.cfg.Algorithm {
+	case Gradient:
+		l.updateGradient(latency, dropped)
+	default:
+		l.updateAIMD(latency, dropped)
+	}
+	l.limit = math.Max(float64(l.cfg.MinLimit), math.Min(float64(l.cfg.MaxLimit), l.limit))
+
+	if current := math.Floor(l.limit); current < previous {
+		LogWarning("adaptive-limit", fmt.Sprintf("%s: %d -> %d", l.key, int(previous), int(current)))
+	}
+
+	l.inFlight--
+	l.wakeWaiters()
+}
+
+// cancel frees a slot taken by a request that was never sent, without adapting the limit
+func (l *adaptiveLimiter) cancel() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	l.wakeWaiters()
+}
+
+// updateAIMD applies additive increase / multiplicative decrease. Callers hold l.mu.
+func (l *adaptiveLimiter) updateAIMD(latency time.Duration, dropped bool) {
+	if dropped || (l.cfg.LatencyThreshold > 0 && latency > l.cfg.LatencyThreshold) {
+		l.limit *= l.backoffRatio()
+		return
+	}
+
+	// Only grow when the limit is actually being used
+	if float64(l.inFlight)*2 >= l.limit {
+		l.limit += 1 / l.limit
+	}
+}
+
+// updateGradient applies a gradient update based on the long-term latency. Callers hold l.mu.
+func (l *adaptiveLimiter) updateGradient(latency time.Duration, dropped bool) {
+	if dropped {
+		l.limit *= l.backoffRatio()
+		return
+	}
+
+	rtt := latency.Seconds()
+	if rtt <= 0 {
+		return
+	}
+	if l.longRTT == 0 {
+		l.longRTT = rtt
+	}
+	l.longRTT = l.longRTT*0.95 + rtt*0.05
+
+	tolerance := l.cfg.Tolerance
+	if tolerance <= 0 {
+		tolerance = 1.5
+	}
+
+	// A gradient below 1 means requests are queueing upstream
+	gradient := math.Max(0.5, math.Min(1, tolerance*l.longRTT/rtt))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+
+	// Do not grow while the limit is not being used
+	if newLimit > l.limit && float64(l.inFlight)*2 < l.limit {
+		return
+	}
+	l.limit = l.limit*0.8 + newLimit*0.2
+}
+
+// backoffRatio returns the configured decrease factor
+func (l *adaptiveLimiter) backoffRatio() float64 {
+	if l.cfg.BackoffRatio > 0 && l.cfg.BackoffRatio < 1 {
+		return l.cfg.BackoffRatio
+	}
+	return 0.9
+}
+
+// wakeWaiters hands free slots to queued requests. Callers hold l.mu.
+func (l *adaptiveLimiter) wakeWaiters() {
+	for len(l.waiters) > 0 && float64(l.inFlight) < math.Floor(l.limit) {
+		waiter := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+		close(waiter)
+	}
+}
@@ -0,0 +1,119 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestBulkhead returns a bulkhead with a fixed limit
+func newTestBulkhead(limit int, maxWait time.Duration) *adaptiveLimiter {
+	return newAdaptiveLimiters(&AdaptiveConcurrencyConfig{
+		InitialLimit: limit,
+		MinLimit:     limit,
+		MaxLimit:     limit,
+		MaxWait:      maxWait,
+	}).get("http://example.com")
+}
+
+// bulkheadState returns the number of slots taken and of queued callers
+func bulkheadState(l *adaptiveLimiter) (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight, len(l.waiters)
+}
+
+func TestBulkheadHandsOffSlot(t *testing.T) {
+	initTestConfig(t, newTestConfig())
+	l := newTestBulkhead(1, time.Second)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- l.acquire(context.Background()) }()
+
+	// Wait until the second caller is queued, then free the slot
+	for {
+		if _, waiting := bulkheadState(l); waiting == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.release(time.Millisecond, false)
+
+	if err := <-acquired; err != nil {
+		t.Fatalf("queued acquire: %v", err)
+	}
+	if inFlight, waiting := bulkheadState(l); inFlight != 1 || waiting != 0 {
+		t.Fatalf("after hand-off: %d in flight, %d waiting; want 1 and 0", inFlight, waiting)
+	}
+}
+
+func TestBulkheadWaitEnds(t *testing.T) {
+	initTestConfig(t, newTestConfig())
+	l := newTestBulkhead(1, 20*time.Millisecond)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	if err := l.acquire(context.Background()); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Fatalf("expected ErrConcurrencyLimited after MaxWait, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+	if err := l.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// Neither waiter kept a slot or a place in the queue
+	if inFlight, waiting := bulkheadState(l); inFlight != 1 || waiting != 0 {
+		t.Fatalf("after giving up: %d in flight, %d waiting; want 1 and 0", inFlight, waiting)
+	}
+
+	// A cancelled request frees its slot without shrinking the limit
+	l.cancel()
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire after cancel: %v", err)
+	}
+}
+
+func TestBulkheadRejectsOverLimit(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	initTestConfig(t, newTestConfig().WithAdaptiveConcurrency(&AdaptiveConcurrencyConfig{
+		InitialLimit: 1,
+		MinLimit:     1,
+		MaxLimit:     1,
+	}))
+
+	first := make(chan error)
+	go func() {
+		_, err := MakeGETRequest("Bulkhead", server.URL, nil, nil)
+		first <- err
+	}()
+	<-entered
+
+	if _, err := MakeGETRequest("Bulkhead", server.URL, nil, nil); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Fatalf("expected ErrConcurrencyLimited, got %v", err)
+	}
+
+	unblock <- struct{}{}
+	if err := <-first; err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+}
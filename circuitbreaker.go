@@ -0,0 +1,298 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CircuitKey selects how requests are grouped into circuit breakers
+type CircuitKey int
+
+const (
+	CircuitPerHost        CircuitKey = iota // One breaker per URL host
+	CircuitPerDescription                   // One breaker per request description
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+// String returns the state name
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrCircuitOpen is matched by errors.Is for every request rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned when a request is rejected without being sent
+type CircuitOpenError struct {
+	Key       string
+	State     CircuitState
+	OpenUntil time.Time
+}
+
+// Error implements the error interface
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s for %q, retry after %s", e.State, e.Key, e.OpenUntil.Format(time.RFC3339))
+}
+
+// Is makes errors.Is(err, ErrCircuitOpen) match
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// circuitBreaker tracks the outcomes of one group of requests
+type circuitBreaker struct {
+	mu  sync.Mutex
+	key string
+	cfg *CircuitBreakerConfig
+
+	state               CircuitState
+	openedAt            time.Time
+	consecutiveFailures int
+
+	// Ring buffer of the last WindowSize outcomes, true meaning failure
+	outcomes []bool
+	next     int
+	count    int
+	failures int
+
+	probesInFlight int
+	probeSuccesses int
+
+	// State changes made under mu, reported once it is released
+	pending []CircuitChangeEvent
+}
+
+// circuitBreakers holds the breakers of the current configuration, keyed by host or description
+type circuitBreakers struct {
+	mu       sync.Mutex
+	cfg      *CircuitBreakerConfig
+	breakers map[string]*circuitBreaker
+}
+
+var breakers *circuitBreakers
+
+// newCircuitBreakers creates the breaker registry for a configuration, or nil when disabled
+func newCircuitBreakers(cfg *CircuitBreakerConfig) *circuitBreakers {
+	if cfg == nil {
+		return nil
+	}
+	return &circuitBreakers{
+		cfg:      cfg,
+		breakers: make(map[string]*circuitBreaker),
+	}
+}
+
+// get returns the breaker for a request, creating it on first use
+func (r *circuitBreakers) get(description, urlStr string) *circuitBreaker {
+	if r == nil {
+		return nil
+	}
+
+	key := description
+	if r.cfg.KeyBy == CircuitPerHost {
+		if u, err := url.Parse(urlStr); err == nil {
+			key = u.Host
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breaker, ok := r.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{
+			key:      key,
+			cfg:      r.cfg,
+			outcomes: make([]bool, r.cfg.WindowSize),
+		}
+		r.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// allow reports whether a request may be sent, moving an open breaker to half-open after the cool-down
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.unlockAndNotify()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cfg.CoolDown {
+			return &CircuitOpenError{Key: b.key, State: b.state, OpenUntil: b.openedAt.Add(b.cfg.CoolDown)}
+		}
+		b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probesInFlight >= b.halfOpenProbes() {
+			return &CircuitOpenError{Key: b.key, State: b.state, OpenUntil: time.Now()}
+		}
+		b.probesInFlight++
+	}
+	return nil
+}
+
+// record registers the outcome of a request that was allowed through
+func (b *circuitBreaker) record(failure bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.unlockAndNotify()
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if b.probesInFlight > 0 {
+			b.probesInFlight--
+		}
+		if failure {
+			b.setState(CircuitOpen)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.halfOpenProbes() {
+			b.setState(CircuitClosed)
+		}
+
+	case CircuitClosed:
+		b.addOutcome(failure)
+		if b.shouldTrip() {
+			b.setState(CircuitOpen)
+		}
+	}
+}
+
+// release frees a half-open probe slot for a request that was allowed but never sent
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+}
+
+// addOutcome updates the consecutive failure count and the sliding window
+func (b *circuitBreaker) addOutcome(failure bool) {
+	if failure {
+		b.consecutiveFailures++
+	} else {
+		b.consecutiveFailures = 0
+	}
+
+	if len(b.outcomes) == 0 {
+		return
+	}
+	if b.count == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.count++
+	}
+	b.outcomes[b.next] = failure
+	if failure {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+}
+
+// shouldTrip reports whether either failure threshold has been reached
+func (b *circuitBreaker) shouldTrip() bool {
+	if b.cfg.ConsecutiveFailures > 0 && b.consecutiveFailures >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	if b.cfg.FailureRateThreshold > 0 && b.count >= b.minRequests() {
+		return float64(b.failures)/float64(b.count) >= b.cfg.FailureRateThreshold
+	}
+	return false
+}
+
+// minRequests returns the requests needed in the window before the failure rate applies
+func (b *circuitBreaker) minRequests() int {
+	if b.cfg.MinRequests > 0 {
+		return b.cfg.MinRequests
+	}
+	return b.cfg.WindowSize
+}
+
+// halfOpenProbes returns the number of probe requests allowed while half-open
+func (b *circuitBreaker) halfOpenProbes() int {
+	if b.cfg.HalfOpenProbes > 0 {
+		return b.cfg.HalfOpenProbes
+	}
+	return 1
+}
+
+// setState changes the state, resetting counters and logging the transition. Callers hold b.mu.
+func (b *circuitBreaker) setState(state CircuitState) {
+	previous := b.state
+	b.state = state
+	b.probesInFlight = 0
+	b.probeSuccesses = 0
+
+	switch state {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+	case CircuitClosed:
+		b.consecutiveFailures = 0
+		b.count, b.next, b.failures = 0, 0, 0
+	}
+
+	LogWarning("circuit-breaker", fmt.Sprintf("%s: %s -> %s", b.key, previous, state))
+	b.pending = append(b.pending, CircuitChangeEvent{Key: b.key, From: previous, To: state})
+}
+
+// unlockAndNotify releases b.mu and then runs the OnCircuitChange hook for pending state changes
+func (b *circuitBreaker) unlockAndNotify() {
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for _, event := range pending {
+		runHook("OnCircuitChange", config.Hooks.onCircuitChange(), event)
+	}
+}
+
+// isCircuitFailure reports whether an attempt outcome counts against the circuit:
+// transport errors and server errors do; client errors, refused redirects and successes do not
+func isCircuitFailure(resp *Response, err error) bool {
+	if err == nil || isRefusedRedirect(err) {
+		return false
+	}
+
+	// Faults caused by the request itself say nothing about the upstream's health
+	var fault *SOAPFault
+	if errors.As(err, &fault) {
+		return fault.isServerFault()
+	}
+	return resp == nil || resp.StatusCode >= 500
+}
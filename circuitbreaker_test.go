@@ -0,0 +1,175 @@
+package network
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOpenCircuitIsNotRetried(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.RetryDelay = time.Second
+	cfg.WithCircuitBreaker(&CircuitBreakerConfig{
+		KeyBy:               CircuitPerDescription,
+		ConsecutiveFailures: 1,
+		CoolDown:            time.Minute,
+	})
+	initTestConfig(t, cfg)
+
+	// The breaker key contains words that once made rejections look like network errors
+	description := "connection timeout service"
+	if _, err := MakeGETRequest(description, server.URL, nil, nil); err == nil {
+		t.Fatal("expected the first request to fail")
+	}
+
+	cfg.RetryConfig.MaxRetries = 3
+	start := time.Now()
+	_, err := MakeGETRequest(description, server.URL, nil, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("open circuit rejection took %s, expected no retry delay", elapsed)
+	}
+}
+
+func TestCircuitBreakerStateTransitions(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var transitions []string
+	coolDown := 50 * time.Millisecond
+	initTestConfig(t, newTestConfig().
+		WithCircuitBreaker(&CircuitBreakerConfig{ConsecutiveFailures: 2, CoolDown: coolDown}).
+		WithHooks(&Hooks{OnCircuitChange: func(e CircuitChangeEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, e.From.String()+"->"+e.To.String())
+		}}))
+
+	call := func() error {
+		_, err := MakeGETRequest("Breaker", server.URL, nil, nil)
+		return err
+	}
+
+	// Two consecutive failures open the circuit, which then rejects without sending
+	call()
+	call()
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("expected 2 requests to reach the server, got %d", n)
+	}
+
+	// A failed probe after the cool-down opens the circuit again
+	time.Sleep(coolDown)
+	if err := call(); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the probe to be sent and fail, got %v", err)
+	}
+	if err := call(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after a failed probe, got %v", err)
+	}
+
+	// A successful probe closes it
+	status.Store(http.StatusOK)
+	time.Sleep(coolDown)
+	if err := call(); err != nil {
+		t.Fatalf("expected the probe to succeed, got %v", err)
+	}
+	if err := call(); err != nil {
+		t.Fatalf("expected a closed circuit, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !reflect.DeepEqual(transitions, want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+}
+
+func TestCircuitBreakerHalfOpenAllowsOneProbe(t *testing.T) {
+	initTestConfig(t, newTestConfig())
+	cfg := &CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Millisecond}
+	breaker := newCircuitBreakers(cfg).get("probe", "http://example.com")
+
+	breaker.allow()
+	breaker.record(true)
+	time.Sleep(2 * time.Millisecond)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the first probe to be allowed, got %v", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a second concurrent probe to be rejected, got %v", err)
+	}
+
+	// A probe that was never sent frees its slot without deciding the state
+	breaker.release()
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the released probe slot to be reused, got %v", err)
+	}
+	breaker.record(false)
+	if breaker.state != CircuitClosed {
+		t.Fatalf("expected closed after a successful probe, got %s", breaker.state)
+	}
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	var status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	// MinRequests defaults to the window size, so a single failure cannot open the circuit
+	initTestConfig(t, newTestConfig().WithCircuitBreaker(&CircuitBreakerConfig{
+		FailureRateThreshold: 0.5,
+		WindowSize:           4,
+		CoolDown:             time.Minute,
+	}))
+
+	for i, code := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK, http.StatusInternalServerError} {
+		status.Store(int32(code))
+		_, err := MakeGETRequest("Get Users", server.URL, nil, nil)
+		if errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("request %d was rejected before the window filled", i+1)
+		}
+	}
+
+	// Two failures out of four reach the 50% threshold
+	status.Store(http.StatusOK)
+	if _, err := MakeGETRequest("Get Users", server.URL, nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen once half the window failed, got %v", err)
+	}
+}
+
+func TestCircuitBreakerMinRequestsValidation(t *testing.T) {
+	cfg := newTestConfig().WithCircuitBreaker(&CircuitBreakerConfig{
+		FailureRateThreshold: 0.5,
+		WindowSize:           4,
+		MinRequests:          5,
+		CoolDown:             time.Minute,
+	})
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected minRequests above windowSize to be rejected")
+	}
+}
@@ -0,0 +1,302 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Codec serializes request payloads and deserializes response bodies for one content type
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Built-in content types
+const (
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// codecRegistry maps media types to codecs, remembering registration order for Accept headers
+type codecRegistry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+	order  []string
+}
+
+var codecs = newCodecRegistry()
+
+// newCodecRegistry creates a registry with the built-in JSON, XML and form codecs
+func newCodecRegistry() *codecRegistry {
+	r := &codecRegistry{codecs: make(map[string]Codec)}
+	r.register(jsonCodec{})
+	r.register(xmlCodec{}, "text/xml")
+	r.register(formCodec{})
+	return r
+}
+
+// register adds codec under its own content type and any aliases
+func (r *codecRegistry) register(codec Codec, aliases ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, contentType := range append([]string{codec.ContentType()}, aliases...) {
+		mediaType := normalizeMediaType(contentType)
+		if _, exists := r.codecs[mediaType]; !exists {
+			r.order = append(r.order, mediaType)
+		}
+		r.codecs[mediaType] = codec
+	}
+}
+
+// lookup finds the codec for a content type, falling back to +json and +xml structured suffixes
+func (r *codecRegistry) lookup(contentType string) (Codec, bool) {
+	mediaType := normalizeMediaType(contentType)
+	if mediaType == "" {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if codec, ok := r.codecs[mediaType]; ok {
+		return codec, true
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		codec, ok := r.codecs[contentTypeJSON]
+		return codec, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		codec, ok := r.codecs["application/xml"]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// accept builds an Accept header listing every registered media type in registration order
+func (r *codecRegistry) accept() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return strings.Join(r.order, ", ")
+}
+
+// RegisterCodec registers a codec for its content type and optional alias content types,
+// replacing any codec previously registered for them
+func RegisterCodec(codec Codec, aliases ...string) {
+	codecs.register(codec, aliases...)
+}
+
+// GetCodec returns the codec registered for a content type; parameters such as charset are ignored
+func GetCodec(contentType string) (Codec, bool) {
+	return codecs.lookup(contentType)
+}
+
+// AcceptHeader returns an Accept header value built from the registered codecs
+func AcceptHeader() string {
+	return codecs.accept()
+}
+
+// normalizeMediaType strips parameters and lowercases a content type
+func normalizeMediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	return strings.ToLower(mediaType)
+}
+
+// isTextContentType reports whether a body of this content type is safe to log as text
+func isTextContentType(contentType string) bool {
+	mediaType := normalizeMediaType(contentType)
+	return mediaType == "" ||
+		strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml") ||
+		mediaType == contentTypeForm
+}
+
+// loggableBody returns the body as-is for text content types and a size summary for binary ones
+func loggableBody(contentType, body string) string {
+	if isTextContentType(contentType) {
+		return body
+	}
+	return fmt.Sprintf("<%s, %d bytes>", normalizeMediaType(contentType), len(body))
+}
+
+// headerValue returns a header from a map using a case-insensitive key match
+func headerValue(headers map[string]string, key string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// jsonCodec encodes with encoding/json
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                        { return contentTypeJSON }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// xmlCodec encodes with encoding/xml
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string { return "application/xml" }
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	encoded, err := EncodeXML(v)
+	return []byte(encoded), err
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+// formCodec encodes url.Values, map[string]string and map[string]interface{} as form data
+type formCodec struct{}
+
+func (formCodec) ContentType() string { return contentTypeForm }
+
+func (formCodec) Marshal(v interface{}) ([]byte, error) {
+	values := url.Values{}
+	switch payload := v.(type) {
+	case url.Values:
+		values = payload
+	case map[string]string:
+		for key, value := range payload {
+			values.Set(key, value)
+		}
+	case map[string]interface{}:
+		for key, value := range payload {
+			values.Set(key, fmt.Sprint(value))
+		}
+	default:
+		return nil, fmt.Errorf("form codec cannot encode %T", v)
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (formCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+	case *map[string]string:
+		*target = make(map[string]string, len(values))
+		for key := range values {
+			(*target)[key] = values.Get(key)
+		}
+	default:
+		return fmt.Errorf("form codec cannot decode into %T", v)
+	}
+	return nil
+}
+
+// MakeTypedRequest encodes payload with the codec matching the declared Content-Type header
+// (JSON when none is declared) and decodes the response into T with the codec matching the
+// response Content-Type. An Accept header listing the registered codecs is added unless set.
+func MakeTypedRequest[T any](method, description, urlStr string, payload interface{}, headers map[string]string) (T, error) {
+	return MakeTypedRequestWithContext[T](context.Background(), method, description, urlStr, payload, headers)
+}
+
+// MakeTypedRequestWithContext is MakeTypedRequest run under ctx, carrying its cancellation and trace into the call
+func MakeTypedRequestWithContext[T any](ctx context.Context, method, description, urlStr string, payload interface{}, headers map[string]string) (T, error) {
+	return makeTypedRequest[T](ctx, method, description, urlStr, payload, headers, nil)
+}
+
+// makeTypedRequest sends a typed request. When codec is set, it encodes the payload whatever the
+// declared Content-Type and decodes responses with a missing or unregistered Content-Type.
+// Otherwise the codec is chosen from the declared Content-Type, a missing response Content-Type
+// falls back to the request codec and an unregistered one is an error.
+func makeTypedRequest[T any](ctx context.Context, method, description, urlStr string, payload interface{}, headers map[string]string, codec Codec) (T, error) {
+	var result T
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return result, err
+	}
+
+	requestHeaders := make(map[string]string, len(headers)+2)
+	for key, value := range headers {
+		requestHeaders[key] = value
+	}
+
+	requestCodec := codec
+	if requestCodec == nil {
+		registered, ok := GetCodec(headerValue(headers, "Content-Type"))
+		if !ok {
+			registered = jsonCodec{}
+		}
+		requestCodec = registered
+	}
+
+	var body bodyFactory
+	var payloadStr string
+	if payload != nil {
+		encoded, err := requestCodec.Marshal(payload)
+		if err != nil {
+			return result, err
+		}
+		body = bytesBody(encoded)
+		payloadStr = loggableBody(requestCodec.ContentType(), string(encoded))
+
+		if headerValue(headers, "Content-Type") == "" {
+			requestHeaders["Content-Type"] = requestCodec.ContentType()
+		}
+	}
+	if headerValue(headers, "Accept") == "" {
+		requestHeaders["Accept"] = AcceptHeader()
+	}
+
+	resp, err := executeRequestForResponse(ctx, strings.ToUpper(method), description, u.String(), body, payloadStr, requestHeaders)
+	if err != nil {
+		return result, err
+	}
+
+	if codec != nil {
+		return decodeResponse[T](resp, codec, false)
+	}
+	return decodeResponse[T](resp, requestCodec, true)
+}
+
+// decodeResponse decodes the response body with the codec for its Content-Type, using fallback
+// when it is absent, or when it has no registered codec and strict is false
+func decodeResponse[T any](resp *Response, fallback Codec, strict bool) (T, error) {
+	var result T
+	if strings.TrimSpace(resp.Body) == "" {
+		return result, nil
+	}
+
+	codec := fallback
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		if registered, ok := GetCodec(contentType); ok {
+			codec = registered
+		} else if strict {
+			return result, fmt.Errorf("no codec registered for response content type %q", contentType)
+		}
+	}
+	if codec == nil {
+		return result, errors.New("no codec available to decode response")
+	}
+
+	if err := codec.Unmarshal([]byte(resp.Body), &result); err != nil {
+		return result, fmt.Errorf("failed to decode %s response: %w", codec.ContentType(), err)
+	}
+	return result, nil
+}
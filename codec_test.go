@@ -0,0 +1,194 @@
+package network
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// withTestCodecs gives a test its own codec registry
+func withTestCodecs(t *testing.T) {
+	saved := codecs
+	codecs = newCodecRegistry()
+	t.Cleanup(func() { codecs = saved })
+}
+
+// upperCodec is a custom codec for tests that upper-cases strings
+type upperCodec struct{}
+
+func (upperCodec) ContentType() string { return "application/vnd.test.upper" }
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return bytes.ToUpper([]byte(v.(string))), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = string(bytes.ToLower(data))
+	return nil
+}
+
+func TestCodecLookup(t *testing.T) {
+	withTestCodecs(t)
+	RegisterCodec(upperCodec{}, "text/x-upper")
+
+	tests := []struct {
+		contentType string
+		want        string // Content type of the codec found, empty when none
+	}{
+		{"application/json", "application/json"},
+		{"Application/JSON; charset=utf-8", "application/json"},
+		{"application/problem+json", "application/json"},
+		{"text/xml", "application/xml"},
+		{"application/atom+xml; charset=utf-8", "application/xml"},
+		{"application/x-www-form-urlencoded", "application/x-www-form-urlencoded"},
+		{"application/vnd.test.upper", "application/vnd.test.upper"},
+		{"text/x-upper", "application/vnd.test.upper"},
+		{"application/vnd.acme.order.v1", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		codec, ok := GetCodec(tt.contentType)
+		got := ""
+		if ok {
+			got = codec.ContentType()
+		}
+		if got != tt.want {
+			t.Errorf("GetCodec(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+
+	want := "application/json, application/xml, text/xml, application/x-www-form-urlencoded, application/vnd.test.upper, text/x-upper"
+	if got := AcceptHeader(); got != want {
+		t.Fatalf("AcceptHeader() = %q, want %q", got, want)
+	}
+}
+
+func TestRegisteredCodecEncodesAndDecodes(t *testing.T) {
+	withTestCodecs(t)
+	RegisterCodec(upperCodec{})
+
+	var received, accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, accept = string(body), r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/vnd.test.upper")
+		io.WriteString(w, "PONG")
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	headers := map[string]string{"Content-Type": "application/vnd.test.upper"}
+	reply, err := MakeTypedRequest[string]("POST", "Ping", server.URL, "ping", headers)
+	if err != nil {
+		t.Fatalf("MakeTypedRequest: %v", err)
+	}
+	if received != "PING" || reply != "pong" {
+		t.Fatalf("sent %q and decoded %q, want PING and pong", received, reply)
+	}
+	if accept != AcceptHeader() {
+		t.Fatalf("Accept = %q, want the registered codecs %q", accept, AcceptHeader())
+	}
+}
+
+func TestTypedRequestKeepsCallerAccept(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	headers := map[string]string{"accept": "application/json"}
+	if _, err := MakeTypedRequest[map[string]bool]("GET", "Get Status", server.URL, nil, headers); err != nil {
+		t.Fatalf("MakeTypedRequest: %v", err)
+	}
+	if accept != "application/json" {
+		t.Fatalf("Accept = %q, want the caller's value", accept)
+	}
+}
+
+func TestFormCodec(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+		io.WriteString(w, "status=created&id=7")
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	payload := map[string]interface{}{"name": "Ada", "age": 36}
+	reply, err := MakeTypedRequest[map[string]string]("POST", "Create User", server.URL, payload, headers)
+	if err != nil {
+		t.Fatalf("MakeTypedRequest: %v", err)
+	}
+	if form.Get("name") != "Ada" || form.Get("age") != "36" {
+		t.Fatalf("server got form %v", form)
+	}
+	if reply["status"] != "created" || reply["id"] != "7" {
+		t.Fatalf("decoded reply %v", reply)
+	}
+
+	if _, err := (formCodec{}).Marshal([]string{"a"}); err == nil {
+		t.Fatal("expected the form codec to refuse a slice")
+	}
+	var values url.Values
+	if err := (formCodec{}).Unmarshal([]byte("a=1&a=2"), &values); err != nil || len(values["a"]) != 2 {
+		t.Fatalf("Unmarshal into url.Values = %v, %v", values, err)
+	}
+}
+
+type xmlTestOrder struct {
+	XMLName xml.Name `xml:"order"`
+	ID      int      `xml:"id"`
+}
+
+func TestXMLRequestEncodesXMLWithVendorContentType(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/vnd.acme.order.v1")
+		io.WriteString(w, `<order><id>2</id></order>`)
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	headers := map[string]string{"Content-Type": "application/vnd.acme.order.v1"}
+	order, err := MakeXMLRequest[xmlTestOrder]("POST", "Create Order", server.URL, xmlTestOrder{ID: 1}, headers)
+	if err != nil {
+		t.Fatalf("MakeXMLRequest: %v", err)
+	}
+	if received != xml.Header+"<order><id>1</id></order>" {
+		t.Fatalf("sent %q, want the XML encoding", received)
+	}
+	if order.ID != 2 {
+		t.Fatalf("decoded order %+v", order)
+	}
+}
+
+func TestMapPayloadFallsBackToJSONForXMLContentType(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	headers := map[string]string{"Content-Type": "application/xml"}
+	if _, err := MakePOSTRequest("Create User", server.URL, map[string]interface{}{"name": "Ada"}, headers); err != nil {
+		t.Fatalf("MakePOSTRequest: %v", err)
+	}
+	if received != `{"name":"Ada"}` {
+		t.Fatalf("sent %q, want the JSON encoding", received)
+	}
+}
@@ -0,0 +1,221 @@
+package network
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ContentEncoding compresses and decompresses bodies for one Content-Encoding token.
+// gzip, deflate, zstd and br are built in; others can be added with RegisterEncoding.
+type ContentEncoding interface {
+	Name() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	encodingsMu    sync.RWMutex
+	encodings      = map[string]ContentEncoding{}
+	encodingsOrder []string
+)
+
+func init() {
+	RegisterEncoding(gzipEncoding{})
+	RegisterEncoding(deflateEncoding{})
+	RegisterEncoding(zstdEncoding{})
+	RegisterEncoding(brotliEncoding{})
+}
+
+// RegisterEncoding registers a content encoding, replacing any encoding with the same name
+func RegisterEncoding(encoding ContentEncoding) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	name := strings.ToLower(encoding.Name())
+	if _, exists := encodings[name]; !exists {
+		encodingsOrder = append(encodingsOrder, name)
+	}
+	encodings[name] = encoding
+}
+
+// getEncoding returns the registered encoding for a Content-Encoding token
+func getEncoding(name string) (ContentEncoding, bool) {
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+
+	encoding, ok := encodings[strings.ToLower(strings.TrimSpace(name))]
+	return encoding, ok
+}
+
+// acceptEncodingHeader returns the Accept-Encoding value from the configuration or all registered encodings
+func acceptEncodingHeader() string {
+	if len(config.CompressionConfig.AcceptEncodings) > 0 {
+		return strings.Join(config.CompressionConfig.AcceptEncodings, ", ")
+	}
+
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+	return strings.Join(encodingsOrder, ", ")
+}
+
+// compressRequestBody compresses a body of known size at or above the configured threshold,
+// returning the new body and a copy of headers with Content-Encoding set
+func compressRequestBody(body io.Reader, headers map[string]string) (io.Reader, map[string]string, []logField, error) {
+	cfg := config.CompressionConfig
+	if cfg == nil || !cfg.CompressRequests || body == nil || headerValue(headers, "Content-Encoding") != "" {
+		return body, headers, nil, nil
+	}
+
+	// Only bodies with a known size are compressed; streamed bodies are sent as-is
+	sized, ok := body.(interface{ Len() int })
+	if !ok || sized.Len() < cfg.MinRequestSize {
+		return body, headers, nil, nil
+	}
+
+	encoding, ok := getEncoding(cfg.RequestEncoding)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported request encoding %q", cfg.RequestEncoding)
+	}
+
+	var compressed bytes.Buffer
+	writer, err := encoding.NewWriter(&compressed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	originalSize, err := io.Copy(writer, body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	compressedHeaders := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		compressedHeaders[key] = value
+	}
+	compressedHeaders["Content-Encoding"] = encoding.Name()
+
+	fields := []logField{{
+		property: "request-encoding",
+		value:    fmt.Sprintf("%s %d -> %d bytes", encoding.Name(), originalSize, compressed.Len()),
+	}}
+	return bytes.NewReader(compressed.Bytes()), compressedHeaders, fields, nil
+}
+
+// decompressResponseBody decodes a body according to its Content-Encoding header, removing
+// the encoding headers once decoded
+func decompressResponseBody(header http.Header, body string) (string, []logField, error) {
+	contentEncoding := header.Get("Content-Encoding")
+	if contentEncoding == "" || body == "" {
+		return body, nil, nil
+	}
+
+	// Encodings are listed in the order they were applied, so undo them in reverse
+	tokens := strings.Split(contentEncoding, ",")
+	data := []byte(body)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := strings.TrimSpace(tokens[i])
+		if token == "" || strings.EqualFold(token, "identity") {
+			continue
+		}
+
+		encoding, ok := getEncoding(token)
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported response content encoding %q", token)
+		}
+
+		reader, err := encoding.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode %s response: %w", token, err)
+		}
+		data, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode %s response: %w", token, err)
+		}
+	}
+
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	fields := []logField{{
+		property: "response-encoding",
+		value:    fmt.Sprintf("%s %d -> %d bytes", contentEncoding, len(body), len(data)),
+	}}
+	return string(data), fields, nil
+}
+
+// gzipEncoding implements the gzip content encoding
+type gzipEncoding struct{}
+
+func (gzipEncoding) Name() string { return "gzip" }
+
+func (gzipEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// deflateEncoding implements the deflate content encoding, which HTTP defines as zlib-wrapped
+// deflate; raw deflate streams sent by non-conforming servers are accepted as well
+type deflateEncoding struct{}
+
+func (deflateEncoding) Name() string { return "deflate" }
+
+func (deflateEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zlib.NewWriter(w), nil
+}
+
+func (deflateEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if reader, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		return reader, nil
+	}
+	return flate.NewReader(bytes.NewReader(data)), nil
+}
+
+// zstdEncoding implements the zstd content encoding
+type zstdEncoding struct{}
+
+func (zstdEncoding) Name() string { return "zstd" }
+
+func (zstdEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+// brotliEncoding implements the br content encoding
+type brotliEncoding struct{}
+
+func (brotliEncoding) Name() string { return "br" }
+
+func (brotliEncoding) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+func (brotliEncoding) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBuiltInEncodingsRoundTrip(t *testing.T) {
+	payload := strings.Repeat(`{"message":"hello"}`, 100)
+
+	for _, name := range []string{"gzip", "deflate", "zstd", "br"} {
+		t.Run(name, func(t *testing.T) {
+			encoding, ok := getEncoding(name)
+			if !ok {
+				t.Fatalf("encoding %q is not registered", name)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Content-Encoding"); got != name {
+					t.Errorf("request Content-Encoding = %q, want %q", got, name)
+				}
+				reader, err := encoding.NewReader(r.Body)
+				if err != nil {
+					t.Errorf("NewReader: %v", err)
+					return
+				}
+				body, err := io.ReadAll(reader)
+				if err != nil {
+					t.Errorf("reading request: %v", err)
+				}
+				reader.Close()
+
+				var compressed bytes.Buffer
+				writer, _ := encoding.NewWriter(&compressed)
+				writer.Write(body)
+				writer.Close()
+
+				w.Header().Set("Content-Encoding", name)
+				w.Write(compressed.Bytes())
+			}))
+			defer server.Close()
+
+			initTestConfig(t, newTestConfig().WithCompression(&CompressionConfig{
+				DecompressResponses: true,
+				CompressRequests:    true,
+				RequestEncoding:     name,
+			}))
+
+			response, err := MakeXMLPostRequest("Echo", server.URL, payload, nil)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if response != payload {
+				t.Fatalf("response was not decoded: %q", response[:min(len(response), 40)])
+			}
+		})
+	}
+}
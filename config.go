@@ -1,8 +1,14 @@
 package network
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"path"
 	"time"
 )
 
@@ -17,6 +23,36 @@ type Config struct {
 	ConnectionConfig *ConnectionConfig
 	RetryConfig      *RetryConfig
 	LoggingConfig    *LoggingConfig
+
+	// Optional features - disabled when nil
+	CompressionConfig         *CompressionConfig
+	CircuitBreakerConfig      *CircuitBreakerConfig
+	RateLimitConfig           *RateLimitConfig
+	AdaptiveConcurrencyConfig *AdaptiveConcurrencyConfig
+	HedgingConfig             *HedgingConfig
+	MetricsConfig             *MetricsConfig
+	RequestIDConfig           *RequestIDConfig
+	CookieConfig              *CookieConfig
+	RedirectConfig            *RedirectConfig
+
+	// Optional request pipeline extensions, applied in order
+	Middlewares []Middleware
+
+	// Optional lifecycle callbacks
+	Hooks *Hooks
+
+	// Optional tracing - no spans are created when nil
+	Tracer Tracer
+
+	// Optional credentials added to every request, overridden per call with WithAuthenticator
+	Authenticator Authenticator
+
+	// Optional transport overrides - at most one may be set. HTTPClient and Transport replace
+	// the transport built from TLSConfig, TimeoutConfig and ConnectionConfig; DialContext only
+	// replaces how connections are opened.
+	HTTPClient  *http.Client
+	Transport   http.RoundTripper
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // TLSConfig holds TLS-related configuration
@@ -45,9 +81,20 @@ type ConnectionConfig struct {
 
 // RetryConfig holds retry mechanism configuration
 type RetryConfig struct {
-	MaxRetries    int
-	RetryDelay    time.Duration
-	RetryOnStatus []int // HTTP status codes to retry on
+	MaxRetries     int
+	RetryDelay     time.Duration
+	RetryOnStatus  []int              // HTTP status codes to retry on
+	AttemptTimeout time.Duration      // Timeout of a single attempt within BaseTimeout, 0 disables
+	MinAttemptTime time.Duration      // Retries start only if this much time is left after RetryDelay, default AttemptTimeout
+	Budget         *RetryBudgetConfig // Optional limit on retries shared across calls
+}
+
+// RetryBudgetConfig limits retries to a fraction of recent requests to prevent retry storms
+type RetryBudgetConfig struct {
+	Ratio               float64       // Retries allowed per request over the window, e.g. 0.1
+	MinRetriesPerSecond float64       // Retries always allowed regardless of traffic
+	Window              time.Duration // Sliding window, default 10s
+	PerHost             bool          // One budget per host instead of one for the whole client
 }
 
 // LoggingConfig holds logging configuration
@@ -57,6 +104,101 @@ type LoggingConfig struct {
 	LogResponseBody  bool
 	LogHeaders       bool
 	SanitizeHeaders  bool
+	LogTimings       bool // Log DNS, connect, TLS, first byte and body transfer times of each response
+}
+
+// CompressionConfig holds request and response compression configuration
+type CompressionConfig struct {
+	DecompressResponses bool     // Decode any registered Content-Encoding instead of relying on Go's implicit gzip
+	AcceptEncodings     []string // Sent as Accept-Encoding; defaults to all registered encodings
+	CompressRequests    bool
+	RequestEncoding     string // Encoding used for request bodies, e.g. "gzip"
+	MinRequestSize      int    // Only bodies of at least this many bytes are compressed
+}
+
+// CircuitBreakerConfig holds circuit breaker configuration
+type CircuitBreakerConfig struct {
+	KeyBy                CircuitKey    // Group requests per host (default) or per description
+	ConsecutiveFailures  int           // Open after this many consecutive failures, 0 disables
+	FailureRateThreshold float64       // Open when this fraction of the window failed (0-1), 0 disables
+	WindowSize           int           // Number of recent requests used for the failure rate
+	MinRequests          int           // Minimum requests in the window before the failure rate applies, default WindowSize
+	CoolDown             time.Duration // Time spent open before probing
+	HalfOpenProbes       int           // Successful probes needed to close again, default 1
+}
+
+// RateLimitConfig holds client-side rate limiting configuration
+type RateLimitConfig struct {
+	Global         *RateLimit            // Shared by every request
+	DefaultPerHost *RateLimit            // Applied to each host without an entry in PerHost
+	PerHost        map[string]*RateLimit // Keyed by URL host, including the port if any
+	Routes         []RouteRateLimit      // First matching route applies
+	FailFast       bool                  // Return ErrRateLimited instead of waiting
+	AdaptToHeaders bool                  // Pause a host on X-RateLimit-Remaining: 0 or Retry-After
+}
+
+// RateLimit is a token bucket and/or in-flight limit
+type RateLimit struct {
+	RequestsPerSecond float64 // 0 means no rate limit
+	Burst             int     // Defaults to RequestsPerSecond rounded up
+	MaxInFlight       int     // 0 means no concurrency limit
+}
+
+// RouteRateLimit applies a limit to URLs matching a path.Match pattern. Patterns starting
+// with "/" match the URL path, others match host and path, e.g. "api.example.com/v1/orders/*".
+type RouteRateLimit struct {
+	Pattern string
+	Limit   RateLimit
+}
+
+// AdaptiveConcurrencyConfig holds the per-host adaptive concurrency limit (bulkhead) configuration
+type AdaptiveConcurrencyConfig struct {
+	Algorithm        AdaptiveAlgorithm // AIMD (default) or Gradient
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	MaxWait          time.Duration // How long a request may queue for a slot, 0 rejects immediately
+	BackoffRatio     float64       // Limit multiplier on errors, default 0.9
+	LatencyThreshold time.Duration // AIMD only: slower responses count as errors, 0 disables
+	Tolerance        float64       // Gradient only: accepted latency increase before shrinking, default 1.5
+}
+
+// HedgingConfig holds hedged request configuration for idempotent methods
+type HedgingConfig struct {
+	Delay      time.Duration // Wait before sending a hedge, used until enough latencies are known
+	Percentile float64       // Use this latency percentile (0-1) of the description as delay, 0 disables
+	MinSamples int           // Latencies needed before Percentile applies
+	MaxHedges  int           // Extra attempts sent, default 1
+	Methods    []string      // Hedged methods, default GET, HEAD and OPTIONS
+}
+
+// MetricsConfig holds metrics collection configuration
+type MetricsConfig struct {
+	Metrics   Metrics  // Receives the measurements, e.g. a Prometheus adapter
+	Routes    []string // Path templates such as "/users/{id}" used as route label; the description is used otherwise
+	MaxRoutes int      // Distinct hosts and routes labelled before new ones are reported as "other", default 100
+}
+
+// RequestIDConfig holds request correlation ID configuration
+type RequestIDConfig struct {
+	Header   string        // Header carrying the ID, default "X-Request-ID"
+	Generate func() string // Creates IDs for calls without one, default 16 random hex characters
+}
+
+// CookieConfig holds cookie jar configuration. Each Config keeps its own session.
+type CookieConfig struct {
+	Jar              http.CookieJar             // Custom jar; an in-memory jar is created when nil
+	PublicSuffixList cookiejar.PublicSuffixList // Used by the in-memory jar, e.g. publicsuffix.List from golang.org/x/net
+	Store            CookieStore                // Persists cookies across restarts
+}
+
+// RedirectConfig holds redirect policy configuration. Go's default policy applies when nil.
+type RedirectConfig struct {
+	Disable                  bool // Return 3xx responses instead of following them
+	MaxRedirects             int  // Redirects followed before failing, default 10
+	SameHostOnly             bool // Refuse redirects to another host
+	AllowDowngrade           bool // Follow redirects from https to http
+	PreserveSensitiveHeaders bool // Keep credential headers on redirects to another host
 }
 
 // NewConfig creates a new configuration with mandatory fields and sensible defaults
@@ -123,6 +265,102 @@ func (c *Config) WithLogging(logConfig *LoggingConfig) *Config {
 	return c
 }
 
+// WithCompression sets the compression configuration
+func (c *Config) WithCompression(compressionConfig *CompressionConfig) *Config {
+	c.CompressionConfig = compressionConfig
+	return c
+}
+
+// WithCircuitBreaker sets the circuit breaker configuration
+func (c *Config) WithCircuitBreaker(circuitBreakerConfig *CircuitBreakerConfig) *Config {
+	c.CircuitBreakerConfig = circuitBreakerConfig
+	return c
+}
+
+// WithRateLimit sets the rate limiting configuration
+func (c *Config) WithRateLimit(rateLimitConfig *RateLimitConfig) *Config {
+	c.RateLimitConfig = rateLimitConfig
+	return c
+}
+
+// WithAdaptiveConcurrency sets the adaptive concurrency configuration
+func (c *Config) WithAdaptiveConcurrency(adaptiveConfig *AdaptiveConcurrencyConfig) *Config {
+	c.AdaptiveConcurrencyConfig = adaptiveConfig
+	return c
+}
+
+// WithHedging sets the hedged request configuration
+func (c *Config) WithHedging(hedgingConfig *HedgingConfig) *Config {
+	c.HedgingConfig = hedgingConfig
+	return c
+}
+
+// WithMetrics sets the metrics configuration
+func (c *Config) WithMetrics(metricsConfig *MetricsConfig) *Config {
+	c.MetricsConfig = metricsConfig
+	return c
+}
+
+// WithRequestID enables request correlation IDs
+func (c *Config) WithRequestID(requestIDConfig *RequestIDConfig) *Config {
+	c.RequestIDConfig = requestIDConfig
+	return c
+}
+
+// WithCookies enables the cookie jar
+func (c *Config) WithCookies(cookieConfig *CookieConfig) *Config {
+	c.CookieConfig = cookieConfig
+	return c
+}
+
+// WithRedirects sets the redirect policy
+func (c *Config) WithRedirects(redirectConfig *RedirectConfig) *Config {
+	c.RedirectConfig = redirectConfig
+	return c
+}
+
+// WithMiddleware appends middlewares to the request pipeline; the first registered runs first
+func (c *Config) WithMiddleware(middlewares ...Middleware) *Config {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+	return c
+}
+
+// WithHooks sets the lifecycle hooks
+func (c *Config) WithHooks(hooks *Hooks) *Config {
+	c.Hooks = hooks
+	return c
+}
+
+// WithAuthenticator authenticates every request with auth
+func (c *Config) WithAuthenticator(auth Authenticator) *Config {
+	c.Authenticator = auth
+	return c
+}
+
+// WithTracer enables tracing of calls and attempts
+func (c *Config) WithTracer(tracer Tracer) *Config {
+	c.Tracer = tracer
+	return c
+}
+
+// WithHTTPClient sends requests through the given client instead of one built from the configuration
+func (c *Config) WithHTTPClient(client *http.Client) *Config {
+	c.HTTPClient = client
+	return c
+}
+
+// WithTransport sends requests through the given RoundTripper instead of one built from the configuration
+func (c *Config) WithTransport(transport http.RoundTripper) *Config {
+	c.Transport = transport
+	return c
+}
+
+// WithDialContext sets the function used to open connections, e.g. to dial a Unix socket
+func (c *Config) WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *Config {
+	c.DialContext = dial
+	return c
+}
+
 // WithInsecureTLS is a convenience method to disable TLS verification
 func (c *Config) WithInsecureTLS() *Config {
 	c.TLSConfig.InsecureSkipVerify = true
@@ -151,9 +389,143 @@ func (c *Config) Validate() error {
 		return errors.New("maxIdleConnsPerHost cannot be negative")
 	}
 	
+	overrides := 0
+	for _, set := range []bool{c.HTTPClient != nil, c.Transport != nil, c.DialContext != nil} {
+		if set {
+			overrides++
+		}
+	}
+	if overrides > 1 {
+		return errors.New("only one of httpClient, transport and dialContext can be set")
+	}
+
+	if c.CookieConfig != nil && c.HTTPClient != nil && c.HTTPClient.Jar != nil {
+		return errors.New("cookieConfig cannot be used with an httpClient that has its own Jar; pass the jar as cookieConfig.Jar")
+	}
+
 	if c.RetryConfig.MaxRetries < 0 {
 		return errors.New("maxRetries cannot be negative")
 	}
+
+	if c.RetryConfig.AttemptTimeout < 0 || c.RetryConfig.MinAttemptTime < 0 {
+		return errors.New("attemptTimeout and minAttemptTime cannot be negative")
+	}
+
+	if c.RetryConfig.Budget != nil {
+		if c.RetryConfig.Budget.Ratio < 0 || c.RetryConfig.Budget.MinRetriesPerSecond < 0 {
+			return errors.New("retry budget ratio and minRetriesPerSecond cannot be negative")
+		}
+
+		if c.RetryConfig.Budget.Window < 0 {
+			return errors.New("retry budget window cannot be negative")
+		}
+	}
+
+	if c.CompressionConfig != nil {
+		if c.CompressionConfig.MinRequestSize < 0 {
+			return errors.New("minRequestSize cannot be negative")
+		}
+
+		if c.CompressionConfig.CompressRequests {
+			if _, ok := getEncoding(c.CompressionConfig.RequestEncoding); !ok {
+				return fmt.Errorf("unsupported requestEncoding %q", c.CompressionConfig.RequestEncoding)
+			}
+		}
+	}
+
+	if c.CircuitBreakerConfig != nil {
+		if c.CircuitBreakerConfig.ConsecutiveFailures <= 0 && c.CircuitBreakerConfig.FailureRateThreshold <= 0 {
+			return errors.New("circuit breaker needs consecutiveFailures or failureRateThreshold")
+		}
+
+		if c.CircuitBreakerConfig.FailureRateThreshold > 1 {
+			return errors.New("failureRateThreshold must be between 0 and 1")
+		}
+
+		if c.CircuitBreakerConfig.FailureRateThreshold > 0 && c.CircuitBreakerConfig.WindowSize <= 0 {
+			return errors.New("windowSize must be greater than 0 when failureRateThreshold is set")
+		}
+
+		if c.CircuitBreakerConfig.MinRequests < 0 || c.CircuitBreakerConfig.MinRequests > c.CircuitBreakerConfig.WindowSize {
+			return errors.New("minRequests must be between 0 and windowSize")
+		}
+
+		if c.CircuitBreakerConfig.CoolDown <= 0 {
+			return errors.New("coolDown must be greater than 0")
+		}
+
+		if c.CircuitBreakerConfig.HalfOpenProbes < 0 {
+			return errors.New("halfOpenProbes cannot be negative")
+		}
+	}
+
+	if c.RateLimitConfig != nil {
+		limits := []*RateLimit{c.RateLimitConfig.Global, c.RateLimitConfig.DefaultPerHost}
+		for _, limit := range c.RateLimitConfig.PerHost {
+			limits = append(limits, limit)
+		}
+		for i, route := range c.RateLimitConfig.Routes {
+			if _, err := path.Match(route.Pattern, ""); err != nil {
+				return fmt.Errorf("invalid route pattern %q: %w", route.Pattern, err)
+			}
+			limits = append(limits, &c.RateLimitConfig.Routes[i].Limit)
+		}
+
+		for _, limit := range limits {
+			if limit == nil {
+				continue
+			}
+			if limit.RequestsPerSecond < 0 || limit.Burst < 0 || limit.MaxInFlight < 0 {
+				return errors.New("rate limits cannot be negative")
+			}
+		}
+	}
+
+	if c.AdaptiveConcurrencyConfig != nil {
+		if c.AdaptiveConcurrencyConfig.MinLimit < 1 {
+			return errors.New("minLimit must be at least 1")
+		}
+
+		if c.AdaptiveConcurrencyConfig.InitialLimit < c.AdaptiveConcurrencyConfig.MinLimit {
+			return errors.New("initialLimit cannot be lower than minLimit")
+		}
+
+		if c.AdaptiveConcurrencyConfig.MaxLimit < c.AdaptiveConcurrencyConfig.InitialLimit {
+			return errors.New("maxLimit cannot be lower than initialLimit")
+		}
+
+		if c.AdaptiveConcurrencyConfig.MaxWait < 0 {
+			return errors.New("maxWait cannot be negative")
+		}
+	}
+
+	if c.MetricsConfig != nil {
+		if c.MetricsConfig.Metrics == nil {
+			return errors.New("metrics collector is required when metrics are enabled")
+		}
+
+		if c.MetricsConfig.MaxRoutes < 0 {
+			return errors.New("maxRoutes cannot be negative")
+		}
+	}
+
+	if c.RedirectConfig != nil && c.RedirectConfig.MaxRedirects < 0 {
+		return errors.New("maxRedirects cannot be negative")
+	}
+
+	if c.HedgingConfig != nil {
+		if c.HedgingConfig.Delay <= 0 {
+			return errors.New("hedging delay must be greater than 0")
+		}
+
+		if c.HedgingConfig.Percentile < 0 || c.HedgingConfig.Percentile >= 1 {
+			return errors.New("hedging percentile must be between 0 and 1")
+		}
+
+		if c.HedgingConfig.MaxHedges < 0 {
+			return errors.New("maxHedges cannot be negative")
+		}
+	}
 	
 	return nil
 }
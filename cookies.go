@@ -0,0 +1,95 @@
+package network
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+)
+
+// CookieStore persists session cookies, e.g. in a file or database, so sessions survive restarts
+type CookieStore interface {
+	// Load returns the cookies saved by previous runs, keyed by the URL that set them
+	Load() (map[string][]*http.Cookie, error)
+	// Save is called with the cookies set by each response
+	Save(u *url.URL, cookies []*http.Cookie) error
+}
+
+// persistentJar is a cookie jar that writes every cookie it receives through to a CookieStore
+type persistentJar struct {
+	jar   http.CookieJar
+	store CookieStore
+}
+
+// SetCookies implements http.CookieJar
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	if err := j.store.Save(u, cookies); err != nil {
+		LogError("cookies", fmt.Sprintf("failed to save cookies for %s: %v", u.Host, err))
+	}
+}
+
+// Cookies implements http.CookieJar
+func (j *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// newCookieJar creates the cookie jar of a configuration, or nil when cookies are disabled
+func newCookieJar(cfg *CookieConfig) (http.CookieJar, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	jar := cfg.Jar
+	if jar == nil {
+		memoryJar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: cfg.PublicSuffixList})
+		if err != nil {
+			return nil, err
+		}
+		jar = memoryJar
+	}
+
+	if cfg.Store == nil {
+		return jar, nil
+	}
+
+	// Restore the saved session before wrapping, so loading does not save everything again
+	saved, err := cfg.Store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cookies: %w", err)
+	}
+	for urlStr, cookies := range saved {
+		u, err := url.Parse(urlStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie URL %q: %w", urlStr, err)
+		}
+		jar.SetCookies(u, cookies)
+	}
+	return &persistentJar{jar: jar, store: cfg.Store}, nil
+}
+
+// withCookieJar returns a copy of client that keeps cookies in jar
+func withCookieJar(client *http.Client, jar http.CookieJar) *http.Client {
+	if jar == nil {
+		return client
+	}
+	withJar := *client
+	withJar.Jar = jar
+	return &withJar
+}
+
+// redactCookies keeps the cookie names of a Cookie or Set-Cookie header but hides their values.
+// Set-Cookie attributes such as Path and Expires are kept.
+func redactCookies(key, value string) string {
+	pairs := strings.Split(value, ";")
+	for i, pair := range pairs {
+		if i > 0 && strings.EqualFold(key, "set-cookie") {
+			break
+		}
+		if name, _, found := strings.Cut(pair, "="); found {
+			pairs[i] = name + "=***"
+		}
+	}
+	return strings.Join(pairs, ";")
+}
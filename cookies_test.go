@@ -0,0 +1,101 @@
+package network
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// memoryCookieStore is a CookieStore for tests that records what it is asked to save
+type memoryCookieStore struct {
+	mu    sync.Mutex
+	saved map[string][]*http.Cookie
+	calls []string // Names of the cookies passed to Save, in order
+}
+
+func (s *memoryCookieStore) Load() (map[string][]*http.Cookie, error) {
+	return s.saved, nil
+}
+
+func (s *memoryCookieStore) Save(u *url.URL, cookies []*http.Cookie) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cookie := range cookies {
+		s.calls = append(s.calls, cookie.Name)
+	}
+	return nil
+}
+
+func TestCookieSessionAcrossCalls(t *testing.T) {
+	var received []string // Cookie header of each call to /me
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/me":
+			received = append(received, r.Header.Get("Cookie"))
+		}
+	}))
+	defer server.Close()
+
+	// A cookie saved by a previous run is restored at Init
+	store := &memoryCookieStore{saved: map[string][]*http.Cookie{
+		server.URL: {{Name: "theme", Value: "dark", Path: "/"}},
+	}}
+	initTestConfig(t, newTestConfig().WithCookies(&CookieConfig{Store: store}))
+
+	if _, err := MakeGETRequest("Get Me", server.URL+"/me", nil, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if _, err := MakePOSTRequest("Login", server.URL+"/login", nil, nil); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := MakeGETRequest("Get Me", server.URL+"/me", nil, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if len(received) != 2 || received[0] != "theme=dark" {
+		t.Fatalf("server got Cookie %q, want the restored cookie first", received)
+	}
+	if received[1] != "theme=dark; session=abc" && received[1] != "session=abc; theme=dark" {
+		t.Fatalf("server got Cookie %q after login, want the session cookie too", received[1])
+	}
+	// Restoring the saved cookies must not save them again
+	if len(store.calls) != 1 || store.calls[0] != "session" {
+		t.Fatalf("Save got cookies %v, want only the session cookie", store.calls)
+	}
+}
+
+func TestRedactCookies(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"Cookie", "session=abc; theme=dark", "session=***; theme=***"},
+		{"Set-Cookie", "session=abc; Path=/; HttpOnly", "session=***; Path=/; HttpOnly"},
+		{"Set-Cookie", "session=abc=def; Expires=Wed, 21 Oct 2026 07:28:00 GMT", "session=***; Expires=Wed, 21 Oct 2026 07:28:00 GMT"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeHeaderValue(tt.key, tt.value); got != tt.want {
+			t.Errorf("sanitizeHeaderValue(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCookiesWithCustomHTTPClientJar(t *testing.T) {
+	jar, _ := cookiejar.New(nil)
+	cfg := newTestConfig().WithHTTPClient(&http.Client{Jar: jar}).WithCookies(&CookieConfig{})
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected a client with its own jar to be rejected together with CookieConfig")
+	}
+
+	// The jar can be kept by passing it through CookieConfig instead
+	cfg = newTestConfig().WithHTTPClient(&http.Client{}).WithCookies(&CookieConfig{Jar: jar})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package network
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// digestAlgorithms lists the supported algorithms, most preferred first
+var digestAlgorithms = []string{"SHA-256", "SHA-256-sess", "MD5", "MD5-sess"}
+
+// DigestAuth authenticates with HTTP Digest access authentication (RFC 7616). The first request
+// to a server gets a 401 challenge, which is answered by resending the request once; the
+// challenge is then cached for that host so later requests authenticate directly with an
+// increasing nonce count. Hosts that never challenged get no credentials.
+type DigestAuth struct {
+	Username string
+	Password string
+
+	mu       sync.Mutex
+	sessions map[string]*digestSession // Keyed by the lower-cased host that sent the challenge
+}
+
+// digestSession is the challenge of one host and the nonce count used with it
+type digestSession struct {
+	challenge  *digestChallenge
+	nonceCount int
+}
+
+// NewDigestAuth creates a Digest authenticator
+func NewDigestAuth(username, password string) *DigestAuth {
+	return &DigestAuth{Username: username, Password: password}
+}
+
+// digestChallenge is a parsed WWW-Authenticate: Digest challenge
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       bool // Whether qop=auth is used; false for legacy RFC 2069 servers
+}
+
+// Authenticate implements Authenticator. Nothing is added until the request's host has challenged.
+func (d *DigestAuth) Authenticate(req *http.Request) error {
+	d.mu.Lock()
+	session := d.sessions[strings.ToLower(req.URL.Host)]
+	if session == nil {
+		d.mu.Unlock()
+		return nil
+	}
+	session.nonceCount++
+	challenge, nonceCount := session.challenge, session.nonceCount
+	d.mu.Unlock()
+
+	req.Header.Set("Authorization", d.authorization(challenge, req.Method, req.URL.RequestURI(), nonceCount, randomRequestID()))
+	return nil
+}
+
+// Challenge implements ChallengeAuthenticator: it caches the Digest challenge for the host that
+// sent it, replacing any earlier realm or stale nonce, so the request can be resent with credentials
+func (d *DigestAuth) Challenge(resp *Response) bool {
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil || resp.requestHost == "" {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sessions == nil {
+		d.sessions = map[string]*digestSession{}
+	}
+	d.sessions[strings.ToLower(resp.requestHost)] = &digestSession{challenge: challenge}
+	return true
+}
+
+// authorization computes the Authorization header for one request
+func (d *DigestAuth) authorization(c *digestChallenge, method, uri string, nonceCount int, cnonce string) string {
+	newHash := md5.New
+	if strings.HasPrefix(c.algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(parts ...string) string {
+		return hexDigest(newHash(), strings.Join(parts, ":"))
+	}
+
+	nc := fmt.Sprintf("%08x", nonceCount)
+	ha1 := h(d.Username, c.realm, d.Password)
+	if strings.HasSuffix(c.algorithm, "-sess") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+	ha2 := h(method, uri)
+
+	var response string
+	if c.qop {
+		response = h(ha1, c.nonce, nc, cnonce, "auth", ha2)
+	} else {
+		response = h(ha1, c.nonce, ha2)
+	}
+
+	params := []string{
+		fmt.Sprintf("username=%q", d.Username),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + c.algorithm,
+		fmt.Sprintf("response=%q", response),
+	}
+	if c.opaque != "" {
+		params = append(params, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+	if c.qop {
+		params = append(params, "qop=auth", "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	return "Digest " + strings.Join(params, ", ")
+}
+
+// hexDigest returns the hex-encoded hash of s
+func hexDigest(h hash.Hash, s string) string {
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseDigestChallenge returns the most preferred usable Digest challenge, or nil
+func parseDigestChallenge(headers []string) *digestChallenge {
+	var best *digestChallenge
+	bestRank := len(digestAlgorithms)
+
+	for _, header := range headers {
+		for _, params := range parseAuthChallenges(header) {
+			if !strings.EqualFold(params[""], "digest") || params["nonce"] == "" {
+				continue
+			}
+
+			algorithm := params["algorithm"]
+			if algorithm == "" {
+				algorithm = "MD5"
+			}
+			rank := -1
+			for i, supported := range digestAlgorithms {
+				if strings.EqualFold(algorithm, supported) {
+					rank = i
+					algorithm = supported
+				}
+			}
+
+			// Only qop=auth is supported; auth-int would require hashing every body
+			qop := params["qop"] != ""
+			if qop && !containsToken(params["qop"], "auth") {
+				continue
+			}
+
+			if rank >= 0 && rank < bestRank {
+				bestRank = rank
+				best = &digestChallenge{
+					realm:     params["realm"],
+					nonce:     params["nonce"],
+					opaque:    params["opaque"],
+					algorithm: algorithm,
+					qop:       qop,
+				}
+			}
+		}
+	}
+	return best
+}
+
+// parseAuthChallenges splits a WWW-Authenticate value into challenges. Each challenge maps its
+// lower-cased parameter names to their unquoted values, with the scheme under the empty key.
+func parseAuthChallenges(header string) []map[string]string {
+	var challenges []map[string]string
+	var current map[string]string
+
+	s := header
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return challenges
+		}
+
+		// Read a token: a scheme or a parameter name
+		end := strings.IndexAny(s, " \t,=")
+		if end < 0 {
+			end = len(s)
+		}
+		token := s[:end]
+		s = strings.TrimLeft(s[end:], " \t")
+
+		if !strings.HasPrefix(s, "=") {
+			current = map[string]string{"": token}
+			challenges = append(challenges, current)
+			continue
+		}
+
+		value, rest := parseAuthParamValue(strings.TrimLeft(s[1:], " \t"))
+		s = rest
+		if current != nil {
+			current[strings.ToLower(token)] = value
+		}
+	}
+}
+
+// parseAuthParamValue reads a token or quoted-string value, returning it and the remaining input
+func parseAuthParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexAny(s, " \t,")
+		if end < 0 {
+			return s, ""
+		}
+		return s[:end], s[end:]
+	}
+
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+			}
+		case '"':
+			return value.String(), s[i+1:]
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return value.String(), ""
+}
+
+// containsToken reports whether a comma-separated list contains token
+func containsToken(list, token string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), token) {
+			return true
+		}
+	}
+	return false
+}
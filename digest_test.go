@@ -0,0 +1,90 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Vectors from RFC 7616 section 3.9.1
+func TestDigestAuthorizationRFC7616(t *testing.T) {
+	auth := &DigestAuth{Username: "Mufasa", Password: "Circle of Life"}
+	const cnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+
+	tests := []struct {
+		algorithm string
+		response  string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+	for _, tt := range tests {
+		challenge := &digestChallenge{
+			realm:     "http-auth@example.org",
+			nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+			opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+			algorithm: tt.algorithm,
+			qop:       true,
+		}
+		got := auth.authorization(challenge, "GET", "/dir/index.html", 1, cnonce)
+		want := `Digest username="Mufasa", realm="http-auth@example.org", ` +
+			`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", uri="/dir/index.html", ` +
+			`algorithm=` + tt.algorithm + `, response="` + tt.response + `", ` +
+			`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS", qop=auth, nc=00000001, cnonce="` + cnonce + `"`
+		if got != want {
+			t.Errorf("%s authorization =\n%s\nwant\n%s", tt.algorithm, got, want)
+		}
+	}
+}
+
+func TestDigestChallengeIsAnsweredPerHost(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{} // Authorization headers by Host
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		mu.Lock()
+		received[r.Host] = append(received[r.Host], authorization)
+		mu.Unlock()
+
+		if !strings.HasPrefix(authorization, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="api", nonce="abc123", qop="auth", algorithm=SHA-256`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig().WithAuthenticator(&DigestAuth{Username: "user", Password: "pass"}))
+
+	// The first call is challenged and resent; the second authenticates directly
+	for i := 0; i < 2; i++ {
+		resp, err := MakeRequestForResponse(context.Background(), "GET", "Get Orders", server.URL+"/orders", nil, nil)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i+1, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i+1, resp.StatusCode)
+		}
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	got := received[host]
+	if len(got) != 3 || got[0] != "" {
+		t.Fatalf("server got Authorization %q, want a challenge and two answers", got)
+	}
+	for i, nc := range []string{"nc=00000001", "nc=00000002"} {
+		if !strings.Contains(got[i+1], `username="user"`) || !strings.Contains(got[i+1], nc) {
+			t.Fatalf("answer %d = %q, want the credentials with %s", i+1, got[i+1], nc)
+		}
+	}
+
+	// The same server under another host name has not challenged, so it gets no credentials
+	otherHost := strings.Replace(host, "127.0.0.1", "localhost", 1)
+	if _, err := MakeGETRequest("Get Orders", "http://"+otherHost+"/orders", nil, nil); err != nil {
+		t.Fatalf("request to %s failed: %v", otherHost, err)
+	}
+	if first := received[otherHost]; len(first) == 0 || first[0] != "" {
+		t.Fatalf("%s got Authorization %q before challenging", otherHost, first)
+	}
+}
@@ -0,0 +1,183 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize is the number of recent latencies kept per description for percentile delays
+const latencyWindowSize = 100
+
+// latencyWindow keeps the most recent successful latencies of one request description
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// add records a latency, overwriting the oldest once the window is full
+func (w *latencyWindow) add(latency time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// percentile returns the latency at percentile p (0-1) once at least minSamples were recorded
+func (w *latencyWindow) percentile(p float64, minSamples int) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.samples) == 0 || len(w.samples) < minSamples {
+		return 0, false
+	}
+
+	sorted := make([]time.Duration, len(w.samples))
+	copy(sorted, w.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	index = int(math.Max(0, math.Min(float64(len(sorted)-1), float64(index))))
+	return sorted[index], true
+}
+
+var (
+	latencyWindowsMu sync.Mutex
+	latencyWindows   = map[string]*latencyWindow{}
+)
+
+// getLatencyWindow returns the latency window of a description, creating it on first use
+func getLatencyWindow(description string) *latencyWindow {
+	latencyWindowsMu.Lock()
+	defer latencyWindowsMu.Unlock()
+
+	window, ok := latencyWindows[description]
+	if !ok {
+		window = &latencyWindow{}
+		latencyWindows[description] = window
+	}
+	return window
+}
+
+// hedgePolicy is the hedging configuration resolved for one request
+type hedgePolicy struct {
+	cfg    *HedgingConfig
+	window *latencyWindow
+}
+
+// newHedgePolicy returns the hedging policy for a request, or nil when the method is not hedged
+func newHedgePolicy(method, description string) *hedgePolicy {
+	cfg := config.HedgingConfig
+	if cfg == nil {
+		return nil
+	}
+
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{methodGET, methodHEAD, methodOPTIONS}
+	}
+	for _, allowed := range methods {
+		if strings.EqualFold(allowed, method) {
+			return &hedgePolicy{cfg: cfg, window: getLatencyWindow(description)}
+		}
+	}
+	return nil
+}
+
+// delay returns how long to wait before sending the next hedge: the configured latency
+// percentile when enough samples exist, the fixed Delay otherwise
+func (h *hedgePolicy) delay() time.Duration {
+	if h.cfg.Percentile > 0 {
+		if latency, ok := h.window.percentile(h.cfg.Percentile, h.cfg.MinSamples); ok {
+			return latency
+		}
+	}
+	return h.cfg.Delay
+}
+
+// maxHedges returns the number of extra attempts allowed, default 1
+func (h *hedgePolicy) maxHedges() int {
+	if h.cfg.MaxHedges > 0 {
+		return h.cfg.MaxHedges
+	}
+	return 1
+}
+
+// hedgeResult is the outcome of one hedged attempt
+type hedgeResult struct {
+	index int // 0 for the original attempt, n for the nth hedge
+	resp  *Response
+	err   error
+}
+
+// executeHedgedAttempt sends an attempt and, while no usable answer has arrived, up to MaxHedges
+// extra copies spaced by the hedge delay. The first usable answer wins and the others are cancelled;
+// if every copy fails the last failure is returned to the retry loop. Cancelled copies are waited
+// for, so none outlives the call or holds on to its rate limit and bulkhead slots.
+func executeHedgedAttempt(ctx context.Context, hedge *hedgePolicy, guards attemptGuards, spec *requestSpec, attempt int) (*Response, error) {
+	hedgeCtx, cancel := context.WithCancel(ctx)
+
+	results := make(chan hedgeResult, hedge.maxHedges()+1)
+	launch := func(index int) {
+		go func() {
+			resp, err := executeGuardedAttempt(hedgeCtx, guards, spec, attempt)
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	launch(0)
+	launched, received := 1, 0
+	defer func() {
+		cancel()
+		for ; received < launched; received++ {
+			<-results
+		}
+	}()
+
+	timer := time.NewTimer(hedge.delay())
+	defer timer.Stop()
+
+	var last hedgeResult
+	for received < launched {
+		select {
+		case result := <-results:
+			received++
+			last = result
+
+			// Anything but a transport or server error is an answer
+			if !isCircuitFailure(result.resp, result.err) {
+				if result.resp != nil {
+					hedge.window.add(result.resp.Duration)
+				}
+				if launched > 1 {
+					logTaggedEntry(spec.requestID, "hedge", fmt.Sprintf("%s: attempt %d of %d won", spec.description, result.index+1, launched), fmt.Sprint)
+				}
+				return result.resp, result.err
+			}
+
+		case <-timer.C:
+			if launched <= hedge.maxHedges() {
+				logTaggedEntry(spec.requestID, "hedge", fmt.Sprintf("%s: no response after %s, sending hedge %d", spec.description, hedge.delay(), launched), warningColor)
+				metrics.hedge(metrics.labels(spec))
+				launch(launched)
+				launched++
+				timer.Reset(hedge.delay())
+			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return last.resp, last.err
+}
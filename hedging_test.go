@@ -0,0 +1,87 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeWinsAndCancelsSlowAttempt(t *testing.T) {
+	var hits atomic.Int32
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		io.WriteString(w, "hedge")
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithHedging(&HedgingConfig{Delay: 20 * time.Millisecond}))
+
+	body, err := MakeGETRequest("Hedge Wins", server.URL, nil, nil)
+	if err != nil || body != "hedge" {
+		t.Fatalf("got %q, %v; want the hedge's answer", body, err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the losing attempt was not cancelled")
+	}
+}
+
+func TestHedgeStopsWhenCallerCancels(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithHedging(&HedgingConfig{Delay: 10 * time.Millisecond, MaxHedges: 2}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := MakeRequestWithContext(ctx, "GET", "Hedge Cancelled", server.URL, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("call returned after %s, expected it to stop at the deadline", elapsed)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("expected the original and 2 hedges, got %d requests", n)
+	}
+}
+
+func TestHedgeSkipsNonIdempotentMethods(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithHedging(&HedgingConfig{Delay: 5 * time.Millisecond}))
+
+	payload := map[string]interface{}{"id": 1}
+	if _, err := MakeRequestWithContext(context.Background(), "POST", "Not Hedged", server.URL, payload, nil); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected a single POST, got %d", n)
+	}
+}
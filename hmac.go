@@ -0,0 +1,162 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HMACAlgorithm selects the hash function of an HMAC signature
+type HMACAlgorithm int
+
+const (
+	HMACSHA256 HMACAlgorithm = iota // Default
+	HMACSHA512
+	HMACSHA1 // Only for partners that still require it
+)
+
+// defaultHMACTemplate is used when HMACConfig.Template is not set
+const defaultHMACTemplate = "{timestamp}\n{method}\n{path}\n{body}"
+
+// HMACConfig describes how requests are signed for a partner API. The canonical string is built
+// from Template by replacing {timestamp}, {nonce}, {method}, {path}, {query}, {host}, {body} and
+// {body_sha256} (hex SHA-256 of the body).
+type HMACConfig struct {
+	Secret    []byte
+	Algorithm HMACAlgorithm
+	Template  string // Canonical string template, default "{timestamp}\n{method}\n{path}\n{body}"
+
+	SignatureHeader string // Default "X-Signature"
+	SignaturePrefix string // Prepended to the signature, e.g. "sha256="
+	Base64          bool   // Encode the signature as base64 instead of hex
+	TimestampHeader string // Default "X-Timestamp"
+	NonceHeader     string // Sends a nonce in this header when set
+	KeyID           string // Sent in KeyIDHeader when both are set
+	KeyIDHeader     string
+
+	Timestamp func(time.Time) string // Formats the signing time, default Unix seconds
+	Nonce     func() string          // Creates nonces, default 16 random hex characters
+}
+
+// HMACSignerMiddleware returns a middleware that signs every attempt, so retries carry a fresh
+// timestamp, nonce and signature
+func HMACSignerMiddleware(cfg *HMACConfig) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if err := SignHMAC(req, cfg, time.Now()); err != nil {
+				return nil, fmt.Errorf("hmac: %w", err)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// SignHMAC signs req in place as of signingTime. The body is read through req.GetBody,
+// so streamed bodies cannot be signed.
+func SignHMAC(req *http.Request, cfg *HMACConfig, signingTime time.Time) error {
+	if len(cfg.Secret) == 0 {
+		return errors.New("secret is required")
+	}
+
+	body, err := replayBody(req)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(signingTime.Unix(), 10)
+	if cfg.Timestamp != nil {
+		timestamp = cfg.Timestamp(signingTime)
+	}
+
+	template := cfg.Template
+	if template == "" {
+		template = defaultHMACTemplate
+	}
+
+	var nonce string
+	if cfg.NonceHeader != "" || strings.Contains(template, "{nonce}") {
+		nonce = randomRequestID()
+		if cfg.Nonce != nil {
+			nonce = cfg.Nonce()
+		}
+	}
+
+	canonical := strings.NewReplacer(
+		"{timestamp}", timestamp,
+		"{nonce}", nonce,
+		"{method}", req.Method,
+		"{path}", req.URL.EscapedPath(),
+		"{query}", req.URL.RawQuery,
+		"{host}", req.URL.Host,
+		"{body}", string(body),
+		"{body_sha256}", hexSHA256(body),
+	).Replace(template)
+
+	mac := hmac.New(cfg.Algorithm.newHash(), cfg.Secret)
+	mac.Write([]byte(canonical))
+	sum := mac.Sum(nil)
+
+	signature := hex.EncodeToString(sum)
+	if cfg.Base64 {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+
+	req.Header.Set(headerOrDefault(cfg.SignatureHeader, "X-Signature"), cfg.SignaturePrefix+signature)
+	req.Header.Set(headerOrDefault(cfg.TimestampHeader, "X-Timestamp"), timestamp)
+	if cfg.NonceHeader != "" {
+		req.Header.Set(cfg.NonceHeader, nonce)
+	}
+	if cfg.KeyIDHeader != "" && cfg.KeyID != "" {
+		req.Header.Set(cfg.KeyIDHeader, cfg.KeyID)
+	}
+	return nil
+}
+
+// newHash returns the hash constructor of the algorithm
+func (a HMACAlgorithm) newHash() func() hash.Hash {
+	switch a {
+	case HMACSHA512:
+		return sha512.New
+	case HMACSHA1:
+		return sha1.New
+	default:
+		return sha256.New
+	}
+}
+
+// replayBody returns a copy of the request body read through GetBody, without consuming req.Body
+func replayBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("streamed request bodies cannot be signed")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// headerOrDefault returns header, or fallback when it is empty
+func headerOrDefault(header, fallback string) string {
+	if header == "" {
+		return fallback
+	}
+	return header
+}
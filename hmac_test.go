@@ -0,0 +1,137 @@
+package network
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Expected signatures were computed independently with Python's hmac module
+func TestSignHMACKnownAnswers(t *testing.T) {
+	const template = "{timestamp}\n{nonce}\n{method}\n{path}\n{query}\n{host}\n{body_sha256}\n{body}"
+	signingTime := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		algorithm HMACAlgorithm
+		base64    bool
+		prefix    string
+		want      string
+	}{
+		{"sha256 hex", HMACSHA256, false, "sha256=", "sha256=49dfac9edb94577cfef4340499d9d00a62516cb83c10c1692e5de45383d58aff"},
+		{"sha256 base64", HMACSHA256, true, "", "Sd+sntuUV3z+9DQEmdnQCmJRbLg8EMFpLl3kU4PViv8="},
+		{"sha512 hex", HMACSHA512, false, "v1=", "v1=586acda96fda71877494447a328e2e1b8b1a5fdcae82acae70f348219ad24ce9bdfd9495b2dffdb8d92559e99c8f8e6c2a51b11081b2e78b309679dc2fe16ff1"},
+		{"sha512 base64", HMACSHA512, true, "", "WGrNqW/acYd0lER6Mo4uG4saX9yugqyucPNIIZrSTOm9/ZSVst/9uNklWemcj45sKlGxEIGy54swlnncL+Fv8Q=="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "https://api.partner.com/v1/orders?x=1", strings.NewReader(`{"id":1}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := &HMACConfig{
+				Secret:          []byte("s3cret"),
+				Algorithm:       tt.algorithm,
+				Template:        template,
+				SignatureHeader: "X-Partner-Signature",
+				SignaturePrefix: tt.prefix,
+				Base64:          tt.base64,
+				NonceHeader:     "X-Nonce",
+				KeyID:           "key-1",
+				KeyIDHeader:     "X-Key-ID",
+				Nonce:           func() string { return "n0nce" },
+			}
+			if err := SignHMAC(req, cfg, signingTime); err != nil {
+				t.Fatalf("SignHMAC: %v", err)
+			}
+
+			if got := req.Header.Get("X-Partner-Signature"); got != tt.want {
+				t.Fatalf("signature = %q, want %q", got, tt.want)
+			}
+			if req.Header.Get("X-Timestamp") != "1700000000" || req.Header.Get("X-Nonce") != "n0nce" || req.Header.Get("X-Key-ID") != "key-1" {
+				t.Fatalf("unexpected headers %v", req.Header)
+			}
+		})
+	}
+}
+
+func TestSignHMACDefaultTemplate(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://api.partner.com/v1/orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignHMAC(req, &HMACConfig{Secret: []byte("s3cret")}, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("SignHMAC: %v", err)
+	}
+	if got, want := req.Header.Get("X-Signature"), "8e4dc622441bb378b48017d95d06620e307e9c10b882234ae355b2c0588e419b"; got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestHMACSignerSignsEveryAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var timestamps, nonces, signatures []string
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		timestamps = append(timestamps, r.Header.Get("X-Timestamp"))
+		nonces = append(nonces, r.Header.Get("X-Nonce"))
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+		mu.Unlock()
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig().WithMiddleware(HMACSignerMiddleware(&HMACConfig{
+		Secret:      []byte("s3cret"),
+		NonceHeader: "X-Nonce",
+		Timestamp:   func(t time.Time) string { return t.Format(time.RFC3339Nano) },
+	}))
+	cfg.RetryConfig.MaxRetries = 1
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	if _, err := MakePOSTRequest("Create Order", server.URL, map[string]interface{}{"id": 1}, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if len(signatures) != 2 {
+		t.Fatalf("server got %d attempts, want 2", len(signatures))
+	}
+	if timestamps[0] == timestamps[1] || nonces[0] == nonces[1] || signatures[0] == signatures[1] {
+		t.Fatalf("retry reused timestamp %q, nonce %q or signature %q", timestamps, nonces, signatures)
+	}
+	for _, nonce := range nonces {
+		if nonce == "" {
+			t.Fatal("an attempt was sent without a nonce")
+		}
+	}
+}
+
+func TestHMACSignerRefusesStreamedBodies(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithMiddleware(HMACSignerMiddleware(&HMACConfig{Secret: []byte("s3cret")})))
+
+	form := NewMultipartForm().AddFileFromBytes("file", "a.txt", bytes.Repeat([]byte("x"), 1024), "")
+	_, err := MakeMultipartPOSTRequest("Upload", server.URL, form, nil)
+	if err == nil || !strings.Contains(err.Error(), "hmac: streamed request bodies cannot be signed") {
+		t.Fatalf("err = %v, want the streamed body error", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("an unsigned request reached the server")
+	}
+}
@@ -0,0 +1,154 @@
+package network
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Hooks are callbacks invoked during the request lifecycle. Every hook is optional. Hooks run
+// synchronously on the request goroutine, so they should return quickly; a panicking hook is
+// recovered and logged.
+type Hooks struct {
+	OnRequest       func(RequestEvent)       // Before each attempt is sent
+	OnRetry         func(RetryEvent)         // Before waiting for each retry
+	OnResponse      func(ResponseEvent)      // After each response is received, whatever its status
+	OnError         func(ErrorEvent)         // After each failed attempt
+	OnGiveUp        func(GiveUpEvent)        // When a call fails for good
+	OnCircuitChange func(CircuitChangeEvent) // When a circuit breaker changes state
+}
+
+// RequestInfo identifies the logical call an event belongs to
+type RequestInfo struct {
+	Method      string
+	Description string
+	URL         string
+	RequestID   string // Empty when request IDs are disabled
+}
+
+// RequestEvent is passed to OnRequest
+type RequestEvent struct {
+	RequestInfo
+	Attempt int
+	Header  http.Header // Copy of the headers sent, without credentials
+}
+
+// RetryEvent is passed to OnRetry
+type RetryEvent struct {
+	RequestInfo
+	Attempt     int // The attempt about to be made, starting at 2
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error // Error of the previous attempt
+}
+
+// ResponseEvent is passed to OnResponse
+type ResponseEvent struct {
+	RequestInfo
+	Attempt    int
+	StatusCode int
+	Header     http.Header
+	Duration   time.Duration
+	BodySize   int
+}
+
+// ErrorEvent is passed to OnError
+type ErrorEvent struct {
+	RequestInfo
+	Attempt    int
+	StatusCode int // 0 when no response was received
+	Err        error
+}
+
+// GiveUpEvent is passed to OnGiveUp
+type GiveUpEvent struct {
+	RequestInfo
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+// CircuitChangeEvent is passed to OnCircuitChange
+type CircuitChangeEvent struct {
+	Key  string
+	From CircuitState
+	To   CircuitState
+}
+
+// info returns the RequestInfo of a request
+func (s *requestSpec) info() RequestInfo {
+	return RequestInfo{
+		Method:      s.method,
+		Description: s.description,
+		URL:         s.url,
+		RequestID:   s.requestID,
+	}
+}
+
+// Nil-safe accessors so call sites do not need to check for missing hooks
+
+func (h *Hooks) onRequest() func(RequestEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnRequest
+}
+
+func (h *Hooks) onRetry() func(RetryEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnRetry
+}
+
+func (h *Hooks) onResponse() func(ResponseEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnResponse
+}
+
+func (h *Hooks) onError() func(ErrorEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnError
+}
+
+func (h *Hooks) onGiveUp() func(GiveUpEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnGiveUp
+}
+
+func (h *Hooks) onCircuitChange() func(CircuitChangeEvent) {
+	if h == nil {
+		return nil
+	}
+	return h.OnCircuitChange
+}
+
+// runHook calls hook with event if it is set, recovering and logging panics
+func runHook[E any](name string, hook func(E), event E) {
+	if hook == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			LogError("hook-panic", fmt.Sprintf("%s: %v", name, r))
+		}
+	}()
+	hook(event)
+}
+
+// notifyGiveUp reports a call that failed for good
+func notifyGiveUp(spec *requestSpec, attempts int, err error, startTime time.Time) {
+	runHook("OnGiveUp", config.Hooks.onGiveUp(), GiveUpEvent{
+		RequestInfo: spec.info(),
+		Attempts:    attempts,
+		Elapsed:     time.Since(startTime),
+		Err:         err,
+	})
+}
@@ -0,0 +1,39 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestHookNeverSeesCredentials(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	var events []RequestEvent
+	cfg := newTestConfig().
+		WithAuthenticator(BearerAuth{Token: "secret"}).
+		WithHooks(&Hooks{OnRequest: func(event RequestEvent) { events = append(events, event) }})
+	initTestConfig(t, cfg)
+
+	if _, err := MakeGETRequest("Get User", server.URL, nil, map[string]string{"Accept": "application/json"}); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if authorization != "Bearer secret" {
+		t.Fatalf("server got Authorization %q, want the bearer token", authorization)
+	}
+	if len(events) != 1 {
+		t.Fatalf("OnRequest ran %d times, want 1", len(events))
+	}
+	// The hook kept the header after returning; credentials added later must not show up in it
+	if got := events[0].Header.Get("Authorization"); got != "" {
+		t.Fatalf("OnRequest header leaked Authorization %q", got)
+	}
+	if got := events[0].Header.Get("Accept"); got != "application/json" {
+		t.Fatalf("OnRequest header Accept = %q, want application/json", got)
+	}
+}
@@ -22,6 +22,7 @@ var (
 	separatorColor = color.New(color.FgHiBlack).SprintFunc()
 	successColor   = color.New(color.FgGreen).SprintFunc()
 	warningColor   = color.New(color.FgYellow).SprintFunc()
+	requestIDColor = color.New(color.FgHiCyan).SprintFunc()
 )
 
 // LogSeparator prints a separator line
@@ -38,8 +39,16 @@ func logEntry(property string, content interface{}) {
 
 // logColoredEntry logs with custom content color
 func logColoredEntry(property string, content interface{}, colorFunc func(a ...interface{}) string) {
+	logTaggedEntry("", property, content, colorFunc)
+}
+
+// logTaggedEntry logs with custom content color, tagging the line with a request ID when set
+func logTaggedEntry(requestID, property string, content interface{}, colorFunc func(a ...interface{}) string) {
 	timestamp := timestampColor(fmt.Sprintf("[%s]", time.Now().Format("2006-01-02 15:04:05")))
 	prop := propertyColor(fmt.Sprintf("[%s]", property))
+	if requestID != "" {
+		prop = requestIDColor(fmt.Sprintf("[%s]", requestID)) + prop
+	}
 	fmt.Printf("%s%s %s\n", timestamp, prop, colorFunc(fmt.Sprintf("%v", content)))
 }
 
@@ -88,26 +97,41 @@ func formatBody(body string) string {
 	return body
 }
 
+// logField is an additional property logged inside a request or response block
+type logField struct {
+	property string
+	value    interface{}
+}
+
+// logFields logs additional properties
+func logFields(requestID string, fields []logField) {
+	for _, field := range fields {
+		logTaggedEntry(requestID, field.property, field.value, methodColor)
+	}
+}
+
 // logRequest logs the outgoing HTTP request with colors
-func logRequest(method, endpoint, description string, headers map[string]string, payload string) {
+func logRequest(requestID, method, endpoint, description string, headers map[string]string, payload string, extras ...logField) {
 	if !config.LoggingConfig.Enabled {
 		return
 	}
 
 	fmt.Println()
 	logSeparator()
-	logColoredEntry("outgoing-request", description, warningColor)
-	logColoredEntry("method", method, methodColor)
-	logColoredEntry("url", endpoint, urlColor)
+	logTaggedEntry(requestID, "outgoing-request", description, warningColor)
+	logTaggedEntry(requestID, "method", method, methodColor)
+	logTaggedEntry(requestID, "url", endpoint, urlColor)
 
 	if config.LoggingConfig.LogHeaders && headers != nil {
 		headerJSON := formatHeaders(headers, config.LoggingConfig.SanitizeHeaders)
-		logColoredEntry("headers", headerJSON, headerColor)
+		logTaggedEntry(requestID, "headers", headerJSON, headerColor)
 	}
 
+	logFields(requestID, extras)
+
 	if config.LoggingConfig.LogRequestBody {
 		formattedBody := formatBody(payload)
-		logColoredEntry("payload", formattedBody, bodyColor)
+		logTaggedEntry(requestID, "payload", formattedBody, bodyColor)
 	}
 
 	logSeparator()
@@ -0,0 +1,193 @@
+package network
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxRoutes is used when MetricsConfig.MaxRoutes is not set
+const defaultMaxRoutes = 100
+
+// overflowLabel replaces label values once the cardinality guard is full
+const overflowLabel = "other"
+
+// Metrics receives measurements of outbound requests. The promnetwork package implements it with
+// the Prometheus client. Every attempt, including retries and hedges, is reported as its own request.
+type Metrics interface {
+	// RequestStarted is called when an attempt starts, e.g. to increment an in-flight gauge
+	RequestStarted(labels MetricLabels)
+	// RequestFinished is called when an attempt ends, whatever its outcome
+	RequestFinished(labels MetricLabels, result MetricResult)
+	// Retry is called before each retry of a call
+	Retry(labels MetricLabels)
+	// Hedge is called when a hedged copy of an attempt is sent
+	Hedge(labels MetricLabels)
+}
+
+// MetricLabels identify the requests a measurement belongs to. Route is a route template
+// or the request description, never the raw URL, so label cardinality stays bounded.
+type MetricLabels struct {
+	Method string
+	Host   string
+	Route  string
+}
+
+// MetricResult is the outcome of one attempt
+type MetricResult struct {
+	StatusClass   string // "2xx" to "5xx", empty when no response was received
+	ErrorKind     string // Empty on success, see the error.type tracing attribute for values
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	Timings       Timings // Zero when no response was received
+}
+
+// metricsRecorder reports measurements to the configured Metrics, guarding label cardinality
+type metricsRecorder struct {
+	cfg    *MetricsConfig
+	routes [][]string // Configured route templates split into path segments
+
+	mu   sync.Mutex
+	seen map[string]map[string]bool // Label values seen so far, by label name
+}
+
+var metrics *metricsRecorder
+
+// newMetricsRecorder creates the recorder for a configuration, or nil when disabled
+func newMetricsRecorder(cfg *MetricsConfig) *metricsRecorder {
+	if cfg == nil {
+		return nil
+	}
+
+	recorder := &metricsRecorder{
+		cfg:  cfg,
+		seen: map[string]map[string]bool{},
+	}
+	for _, route := range cfg.Routes {
+		recorder.routes = append(recorder.routes, strings.Split(strings.Trim(route, "/"), "/"))
+	}
+	return recorder
+}
+
+// labels returns the labels of a request
+func (m *metricsRecorder) labels(spec *requestSpec) MetricLabels {
+	if m == nil {
+		return MetricLabels{}
+	}
+
+	labels := MetricLabels{Method: spec.method, Route: spec.description}
+	if u, err := url.Parse(spec.url); err == nil {
+		labels.Host = u.Host
+		if route, ok := m.matchRoute(u.Path); ok {
+			labels.Route = route
+		}
+	}
+
+	labels.Host = m.guard("host", labels.Host)
+	labels.Route = m.guard("route", labels.Route)
+	return labels
+}
+
+// matchRoute returns the first configured route template matching path. A segment
+// written as {name} matches any single non-empty path segment.
+func (m *metricsRecorder) matchRoute(path string) (string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, template := range m.routes {
+		if len(template) != len(segments) {
+			continue
+		}
+
+		matched := true
+		for j, part := range template {
+			isParam := strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")
+			if !(isParam && segments[j] != "") && part != segments[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return m.cfg.Routes[i], true
+		}
+	}
+	return "", false
+}
+
+// guard returns value while fewer than MaxRoutes distinct values were seen for the label, "other" afterwards
+func (m *metricsRecorder) guard(label, value string) string {
+	limit := m.cfg.MaxRoutes
+	if limit <= 0 {
+		limit = defaultMaxRoutes
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values, ok := m.seen[label]
+	if !ok {
+		values = map[string]bool{}
+		m.seen[label] = values
+	}
+	if values[value] {
+		return value
+	}
+	if len(values) >= limit {
+		return overflowLabel
+	}
+	values[value] = true
+	return value
+}
+
+// started reports the start of an attempt
+func (m *metricsRecorder) started(labels MetricLabels) {
+	if m == nil {
+		return
+	}
+	m.cfg.Metrics.RequestStarted(labels)
+}
+
+// finished reports the outcome of an attempt
+func (m *metricsRecorder) finished(labels MetricLabels, resp *Response, err error, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	result := MetricResult{
+		ErrorKind: errorType(resp, err),
+		Duration:  duration,
+	}
+	if resp != nil {
+		result.StatusClass = statusClass(resp.StatusCode)
+		result.Duration = resp.Duration
+		result.BytesSent = resp.BytesSent
+		result.BytesReceived = resp.BytesReceived
+		result.Timings = resp.Timings
+	}
+	m.cfg.Metrics.RequestFinished(labels, result)
+}
+
+// retry reports a retry
+func (m *metricsRecorder) retry(labels MetricLabels) {
+	if m == nil {
+		return
+	}
+	m.cfg.Metrics.Retry(labels)
+}
+
+// hedge reports a hedge
+func (m *metricsRecorder) hedge(labels MetricLabels) {
+	if m == nil {
+		return
+	}
+	m.cfg.Metrics.Hedge(labels)
+}
+
+// statusClass returns the class of a status code, e.g. "2xx"
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "other"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
@@ -0,0 +1,44 @@
+package network
+
+import "net/http"
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the next RoundTripper in the chain. It is invoked once per attempt with the
+// fully built request, after it has been logged, and sees the final response after redirects.
+// Middlewares must not modify the incoming request; clone it with req.Clone to change it.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// roundTripper sends every attempt through the configured middleware chain
+var roundTripper http.RoundTripper
+
+// buildMiddlewareChain wraps final with middlewares so that the first one registered runs first
+func buildMiddlewareChain(middlewares []Middleware, final http.RoundTripper) http.RoundTripper {
+	chain := final
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		chain = middlewares[i](chain)
+	}
+	return chain
+}
+
+// HeaderMiddleware returns a middleware that sets the given headers on every attempt,
+// without overriding headers already present on the request
+func HeaderMiddleware(headers map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for key, value := range headers {
+				if req.Header.Get(key) == "" {
+					req.Header.Set(key, value)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingMiddleware appends name to calls each time it runs
+func recordingMiddleware(name string, mu *sync.Mutex, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			*calls = append(*calls, name)
+			mu.Unlock()
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestMiddlewareOrderAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	cfg := newTestConfig().
+		WithMiddleware(recordingMiddleware("first", &mu, &calls)).
+		WithMiddleware(recordingMiddleware("second", &mu, &calls))
+	cfg.RetryConfig.MaxRetries = 1
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	if _, err := MakeGETRequest("Get Users", server.URL, nil, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// The first registered middleware runs first, and the whole chain runs once per attempt
+	want := []string{"first", "second", "first", "second"}
+	if len(calls) != len(want) {
+		t.Fatalf("middleware calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("middleware calls = %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithMiddleware(HeaderMiddleware(map[string]string{
+		"X-Client":  "billing",
+		"X-Version": "2",
+	})))
+
+	if _, err := MakeGETRequest("Get Users", server.URL, nil, map[string]string{"X-Version": "3"}); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if header.Get("X-Client") != "billing" {
+		t.Fatalf("X-Client = %q, want the middleware header", header.Get("X-Client"))
+	}
+	if header.Get("X-Version") != "3" {
+		t.Fatalf("X-Version = %q, want the caller's value kept", header.Get("X-Version"))
+	}
+}
@@ -0,0 +1,328 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MultipartForm describes a multipart/form-data body made of text fields and file parts
+type MultipartForm struct {
+	Fields []FormField
+	Files  []FilePart
+
+	boundary string
+}
+
+// FormField is a plain text part of a multipart form
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// FilePart is a file part of a multipart form. Exactly one source must be set:
+// Path, Data, Reader or Open. Path, Data and Open sources can be re-read on retry,
+// Reader only if it also implements io.Seeker.
+type FilePart struct {
+	FieldName   string
+	FileName    string
+	ContentType string // Detected from the file extension when empty
+
+	Path   string
+	Data   []byte
+	Reader io.Reader
+	Open   func() (io.ReadCloser, error)
+
+	readerUsed bool
+}
+
+// NewMultipartForm creates an empty multipart form
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{}
+}
+
+// AddField adds a text field to the form
+func (f *MultipartForm) AddField(name, value string) *MultipartForm {
+	f.Fields = append(f.Fields, FormField{Name: name, Value: value})
+	return f
+}
+
+// AddFileFromPath adds a file part that is streamed from disk on every attempt
+func (f *MultipartForm) AddFileFromPath(fieldName, path, contentType string) *MultipartForm {
+	f.Files = append(f.Files, FilePart{
+		FieldName:   fieldName,
+		FileName:    filepath.Base(path),
+		ContentType: contentType,
+		Path:        path,
+	})
+	return f
+}
+
+// AddFileFromBytes adds an in-memory file part
+func (f *MultipartForm) AddFileFromBytes(fieldName, fileName string, data []byte, contentType string) *MultipartForm {
+	f.Files = append(f.Files, FilePart{
+		FieldName:   fieldName,
+		FileName:    fileName,
+		ContentType: contentType,
+		Data:        data,
+	})
+	return f
+}
+
+// AddFileFromReader adds a file part read from r. Unless r is an io.Seeker the
+// request cannot be retried once the reader has been consumed.
+func (f *MultipartForm) AddFileFromReader(fieldName, fileName string, r io.Reader, contentType string) *MultipartForm {
+	f.Files = append(f.Files, FilePart{
+		FieldName:   fieldName,
+		FileName:    fileName,
+		ContentType: contentType,
+		Reader:      r,
+	})
+	return f
+}
+
+// AddFileFromOpener adds a file part whose source is re-opened for every attempt
+func (f *MultipartForm) AddFileFromOpener(fieldName, fileName string, open func() (io.ReadCloser, error), contentType string) *MultipartForm {
+	f.Files = append(f.Files, FilePart{
+		FieldName:   fieldName,
+		FileName:    fileName,
+		ContentType: contentType,
+		Open:        open,
+	})
+	return f
+}
+
+// contentType returns the Content-Type header value including the form boundary
+func (f *MultipartForm) contentType() string {
+	return "multipart/form-data; boundary=" + f.getBoundary()
+}
+
+// getBoundary returns the form boundary, generating it once so every attempt uses the same one
+func (f *MultipartForm) getBoundary() string {
+	if f.boundary == "" {
+		f.boundary = multipart.NewWriter(io.Discard).Boundary()
+	}
+	return f.boundary
+}
+
+// validate checks that every file part has exactly one source
+func (f *MultipartForm) validate() error {
+	for i, file := range f.Files {
+		if file.FieldName == "" {
+			return fmt.Errorf("file part %d: fieldName is required", i)
+		}
+
+		sources := 0
+		if file.Path != "" {
+			sources++
+		}
+		if file.Data != nil {
+			sources++
+		}
+		if file.Reader != nil {
+			sources++
+		}
+		if file.Open != nil {
+			sources++
+		}
+		if sources != 1 {
+			return fmt.Errorf("file part %q must have exactly one source", file.FieldName)
+		}
+	}
+	return nil
+}
+
+// open returns a reader for the part source, re-opening or rewinding it when possible
+func (p *FilePart) open() (io.ReadCloser, error) {
+	switch {
+	case p.Path != "":
+		return os.Open(p.Path)
+	case p.Data != nil:
+		return io.NopCloser(bytes.NewReader(p.Data)), nil
+	case p.Open != nil:
+		return p.Open()
+	case p.Reader != nil:
+		if p.readerUsed {
+			seeker, ok := p.Reader.(io.Seeker)
+			if !ok {
+				return nil, fmt.Errorf("file part %q cannot be re-read for retry", p.FieldName)
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		p.readerUsed = true
+		return io.NopCloser(p.Reader), nil
+	}
+	return nil, errors.New("file part has no source")
+}
+
+// size returns the part size if it is known without reading it, otherwise -1
+func (p *FilePart) size() int64 {
+	switch {
+	case p.Data != nil:
+		return int64(len(p.Data))
+	case p.Path != "":
+		if info, err := os.Stat(p.Path); err == nil {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// partContentType returns the configured content type or one detected from the file name
+func (p *FilePart) partContentType() string {
+	if p.ContentType != "" {
+		return p.ContentType
+	}
+	if detected := mime.TypeByExtension(filepath.Ext(p.FileName)); detected != "" {
+		return detected
+	}
+	return "application/octet-stream"
+}
+
+// bodyFactory returns a factory that streams the form through a pipe on every attempt
+func (f *MultipartForm) bodyFactory() bodyFactory {
+	return func() (io.Reader, error) {
+		// Open every source up front so missing files fail before the request is sent
+		sources := make([]io.ReadCloser, 0, len(f.Files))
+		for i := range f.Files {
+			src, err := f.Files[i].open()
+			if err != nil {
+				for _, opened := range sources {
+					opened.Close()
+				}
+				return nil, err
+			}
+			sources = append(sources, src)
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			defer func() {
+				for _, src := range sources {
+					src.Close()
+				}
+			}()
+			pw.CloseWithError(f.write(pw, sources))
+		}()
+
+		return pr, nil
+	}
+}
+
+// write encodes all fields and file parts into w
+func (f *MultipartForm) write(w io.Writer, sources []io.ReadCloser) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(f.getBoundary()); err != nil {
+		return err
+	}
+
+	for _, field := range f.Fields {
+		if err := mw.WriteField(field.Name, field.Value); err != nil {
+			return err
+		}
+	}
+
+	for i, file := range f.Files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.FieldName), escapeQuotes(file.FileName)))
+		h.Set("Content-Type", file.partContentType())
+
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, sources[i]); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+// describe returns a JSON summary of the form for logging, without field values or file contents
+func (f *MultipartForm) describe() string {
+	type fileMeta struct {
+		Field       string `json:"field"`
+		FileName    string `json:"filename"`
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size,omitempty"`
+	}
+
+	fields := make([]string, 0, len(f.Fields))
+	for _, field := range f.Fields {
+		fields = append(fields, field.Name)
+	}
+
+	files := make([]fileMeta, 0, len(f.Files))
+	for i := range f.Files {
+		meta := fileMeta{
+			Field:       f.Files[i].FieldName,
+			FileName:    f.Files[i].FileName,
+			ContentType: f.Files[i].partContentType(),
+		}
+		if size := f.Files[i].size(); size >= 0 {
+			meta.Size = size
+		}
+		files = append(files, meta)
+	}
+
+	summary, err := json.Marshal(map[string]interface{}{
+		"fields": fields,
+		"files":  files,
+	})
+	if err != nil {
+		return "multipart form"
+	}
+	return string(summary)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes quotes in Content-Disposition parameters the same way mime/multipart does
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// makeMultipartRequest sends a multipart form with the given method
+func makeMultipartRequest(method, description, urlStr string, form *MultipartForm, headers map[string]string) (string, error) {
+	if form == nil {
+		return "", errors.New("multipart form is nil")
+	}
+	if err := form.validate(); err != nil {
+		return "", fmt.Errorf("invalid multipart form: %w", err)
+	}
+
+	// Copy headers so the caller's map is not modified
+	requestHeaders := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		if strings.EqualFold(key, "Content-Type") {
+			continue
+		}
+		requestHeaders[key] = value
+	}
+	requestHeaders["Content-Type"] = form.contentType()
+
+	return executeRequest(context.Background(), method, description, urlStr, form.bodyFactory(), form.describe(), requestHeaders)
+}
+
+// MakeMultipartPOSTRequest sends a multipart/form-data POST request, streaming file parts
+func MakeMultipartPOSTRequest(description, url string, form *MultipartForm, headers map[string]string) (string, error) {
+	return makeMultipartRequest(methodPOST, description, url, form, headers)
+}
+
+// MakeMultipartPUTRequest sends a multipart/form-data PUT request, streaming file parts
+func MakeMultipartPUTRequest(description, url string, form *MultipartForm, headers map[string]string) (string, error) {
+	return makeMultipartRequest(methodPUT, description, url, form, headers)
+}
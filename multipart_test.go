@@ -0,0 +1,188 @@
+package network
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMultipartBodyClosedWhenNotSent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// An empty bearer token fails before the request is sent
+	initTestConfig(t, newTestConfig().WithAuthenticator(BearerAuth{}))
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		form := &MultipartForm{Files: []FilePart{{
+			FieldName: "file",
+			FileName:  "large.bin",
+			Data:      bytes.Repeat([]byte("x"), 1<<20),
+		}}}
+		if _, err := MakeMultipartPOSTRequest("Upload", server.URL, form, nil); err == nil {
+			t.Fatal("expected authentication error")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked > 0 {
+		t.Fatalf("%d multipart writer goroutines leaked", leaked)
+	}
+}
+
+// receivedPart is a multipart part as parsed by the server
+type receivedPart struct {
+	name        string
+	fileName    string
+	contentType string
+	body        string
+}
+
+// readParts parses a multipart/form-data request body
+func readParts(t *testing.T, r *http.Request) []receivedPart {
+	t.Helper()
+	reader, err := r.MultipartReader()
+	if err != nil {
+		t.Errorf("MultipartReader: %v", err)
+		return nil
+	}
+
+	var parts []receivedPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Errorf("NextPart: %v", err)
+			return parts
+		}
+		body, _ := io.ReadAll(part)
+		parts = append(parts, receivedPart{
+			name:        part.FormName(),
+			fileName:    part.FileName(),
+			contentType: part.Header.Get("Content-Type"),
+			body:        string(body),
+		})
+	}
+}
+
+func TestMultipartWireFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte(`{"a":1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var parts []receivedPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts = readParts(t, r)
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	form := NewMultipartForm().
+		AddField("customer", "42").
+		AddFileFromBytes("photo", `me "at" home.png`, []byte("png"), "").
+		AddFileFromPath("report", path, "").
+		AddFileFromReader("notes", "notes.bin", strings.NewReader("streamed"), "application/x-notes").
+		AddFileFromOpener("extra", `back\slash`, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("opened")), nil
+		}, "")
+	if _, err := MakeMultipartPOSTRequest("Upload", server.URL, form, nil); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	expected := []receivedPart{
+		{name: "customer", body: "42"},
+		{name: "photo", fileName: `me "at" home.png`, contentType: "image/png", body: "png"},
+		{name: "report", fileName: "report.json", contentType: "application/json", body: `{"a":1}`},
+		{name: "notes", fileName: "notes.bin", contentType: "application/x-notes", body: "streamed"},
+		{name: "extra", fileName: `back\slash`, contentType: "application/octet-stream", body: "opened"},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("server got %d parts, want %d: %+v", len(parts), len(expected), parts)
+	}
+	for i, want := range expected {
+		if parts[i] != want {
+			t.Errorf("part %d = %+v, want %+v", i, parts[i], want)
+		}
+	}
+}
+
+func TestMultipartRetryReopensSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("from disk"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var attempts atomic.Int32
+	var lastParts []receivedPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastParts = readParts(t, r)
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.MaxRetries = 1
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	opens := 0
+	form := NewMultipartForm().
+		AddFileFromPath("doc", path, "").
+		AddFileFromOpener("generated", "gen.txt", func() (io.ReadCloser, error) {
+			opens++
+			return io.NopCloser(strings.NewReader("generated")), nil
+		}, "").
+		AddFileFromReader("seekable", "seek.txt", strings.NewReader("seekable"), "")
+	if _, err := MakeMultipartPOSTRequest("Upload", server.URL, form, nil); err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	if attempts.Load() != 2 || opens != 2 {
+		t.Fatalf("attempts = %d, opens = %d; want both 2", attempts.Load(), opens)
+	}
+	bodies := map[string]string{}
+	for _, part := range lastParts {
+		bodies[part.name] = part.body
+	}
+	if bodies["doc"] != "from disk" || bodies["generated"] != "generated" || bodies["seekable"] != "seekable" {
+		t.Fatalf("retried attempt sent %v, want every source re-read in full", bodies)
+	}
+}
+
+func TestMultipartNonSeekableReaderCannotBeRetried(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.MaxRetries = 1
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	// Hide the Seeker of the strings.Reader
+	stream := struct{ io.Reader }{strings.NewReader("once")}
+	form := NewMultipartForm().AddFileFromReader("stream", "stream.txt", stream, "")
+	_, err := MakeMultipartPOSTRequest("Upload", server.URL, form, nil)
+	if err == nil || !strings.Contains(err.Error(), `file part "stream" cannot be re-read for retry`) {
+		t.Fatalf("err = %v, want the re-read error", err)
+	}
+}
@@ -3,7 +3,7 @@ package network
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -25,20 +25,48 @@ func Init(cfg *Config) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	jar, err := newCookieJar(cfg.CookieConfig)
+	if err != nil {
+		return fmt.Errorf("invalid cookie configuration: %w", err)
+	}
+
 	config = cfg
-	httpClient = createHTTPClient(cfg)
+	httpClient = withRedirectPolicy(withCookieJar(createHTTPClient(cfg), jar), cfg.RedirectConfig)
+	roundTripper = buildMiddlewareChain(cfg.Middlewares, RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return httpClient.Do(req)
+	}))
+	breakers = newCircuitBreakers(cfg.CircuitBreakerConfig)
+	limiters = newRateLimiters(cfg.RateLimitConfig)
+	bulkheads = newAdaptiveLimiters(cfg.AdaptiveConcurrencyConfig)
+	budgets = newRetryBudgets(cfg.RetryConfig.Budget)
+	metrics = newMetricsRecorder(cfg.MetricsConfig)
 	isInitialized = true
 	return nil
 }
 
 // createHTTPClient creates an HTTP client based on the configuration
 func createHTTPClient(cfg *Config) *http.Client {
-	dialer := &net.Dialer{
-		Timeout: cfg.TimeoutConfig.DialTimeout,
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+
+	if cfg.Transport != nil {
+		return &http.Client{
+			Timeout:   cfg.BaseTimeout,
+			Transport: cfg.Transport,
+		}
+	}
+
+	dialContext := cfg.DialContext
+	if dialContext == nil {
+		dialer := &net.Dialer{
+			Timeout: cfg.TimeoutConfig.DialTimeout,
+		}
+		dialContext = dialer.DialContext
 	}
 
 	transport := &http.Transport{
-		Dial:                  dialer.Dial,
+		DialContext:           dialContext,
 		TLSHandshakeTimeout:   cfg.TimeoutConfig.TLSHandshakeTimeout,
 		ResponseHeaderTimeout: cfg.TimeoutConfig.ResponseHeaderTimeout,
 		ExpectContinueTimeout: cfg.TimeoutConfig.ExpectContinueTimeout,
@@ -68,25 +96,45 @@ func ensureInitialized() {
 
 // sanitizeHeaderValue truncates sensitive header values for logging
 func sanitizeHeaderValue(key, value string) string {
-	sensitiveHeaders := []string{"authorization", "auth", "token", "api-key", "x-api-key", "bearer"}
+	lowerKey := strings.ToLower(key)
+
+	if lowerKey == "cookie" || lowerKey == "set-cookie" {
+		return redactCookies(lowerKey, value)
+	}
+
+	if isSensitiveHeader(key) {
+		if len(value) > 10 {
+			return value[:10] + "..."
+		}
+		return "***"
+	}
+	return value
+}
+
+// isSensitiveHeader reports whether a header carries credentials
+func isSensitiveHeader(key string) bool {
+	sensitiveHeaders := []string{"authorization", "auth", "token", "api-key", "x-api-key", "bearer", "cookie"}
 	lowerKey := strings.ToLower(key)
 
 	for _, sensitive := range sensitiveHeaders {
 		if strings.Contains(lowerKey, sensitive) {
-			if len(value) > 10 {
-				return value[:10] + "..."
-			}
-			return "***"
+			return true
 		}
 	}
-	return value
+	return false
 }
 
 // Add a common request handler
-func makeRequest(method, description, urlStr string, payload map[string]interface{}, headers map[string]string) (string, error) {
+func makeRequest(ctx context.Context, method, description, urlStr string, payload map[string]interface{}, headers map[string]string) (string, error) {
+	resp, err := makeRequestForResponse(ctx, method, description, urlStr, payload, headers)
+	return resp.responseBody(), err
+}
+
+// makeRequestForResponse is makeRequest returning the full response
+func makeRequestForResponse(ctx context.Context, method, description, urlStr string, payload map[string]interface{}, headers map[string]string) (*Response, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Methods that typically don't have a request body should use query parameters
@@ -101,22 +149,34 @@ func makeRequest(method, description, urlStr string, payload map[string]interfac
 	}
 
 	// Prepare request body for methods that typically have one
-	var body io.Reader
+	var body bodyFactory
 	var payloadStr string
 	if !isQueryParamMethod && payload != nil {
-		jsonPayload, err := json.Marshal(payload)
+		// Encode with the codec matching the declared Content-Type, JSON otherwise
+		contentType := headerValue(headers, "Content-Type")
+		codec, ok := GetCodec(contentType)
+		if !ok {
+			codec = jsonCodec{}
+		}
+
+		encodedPayload, err := codec.Marshal(payload)
+		if err != nil && codec.ContentType() != contentTypeJSON {
+			// Codecs such as XML cannot encode a map; keep sending JSON as these functions always did
+			codec = jsonCodec{}
+			encodedPayload, err = codec.Marshal(payload)
+		}
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		body = bytes.NewBuffer(jsonPayload)
-		payloadStr = string(jsonPayload)
+		body = bytesBody(encodedPayload)
+		payloadStr = loggableBody(codec.ContentType(), string(encodedPayload))
 	}
 
-	return executeRequest(method, description, u.String(), body, payloadStr, headers)
+	return executeRequestForResponse(ctx, method, description, u.String(), body, payloadStr, headers)
 }
 
 // Add a string payload variant
-func makeRequestWithString(method, description, urlStr string, payload string, headers map[string]string) (string, error) {
+func makeRequestWithString(ctx context.Context, method, description, urlStr string, payload string, headers map[string]string) (string, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return "", err
@@ -125,76 +185,363 @@ func makeRequestWithString(method, description, urlStr string, payload string, h
 	// Methods that typically don't have a request body should use query parameters
 	isQueryParamMethod := method == methodGET || method == methodDELETE || method == methodHEAD || method == methodOPTIONS
 
-	var body io.Reader
+	var body bodyFactory
 	var payloadStr string
 	if !isQueryParamMethod && payload != "" {
 		quotedPayload := "\"" + payload + "\""
-		body = bytes.NewBuffer([]byte(quotedPayload))
+		body = bytesBody([]byte(quotedPayload))
 		payloadStr = quotedPayload
 	}
 
-	return executeRequest(method, description, u.String(), body, payloadStr, headers)
+	return executeRequest(ctx, method, description, u.String(), body, payloadStr, headers)
+}
+
+// bodyFactory returns a fresh request body for each attempt so retries never reuse a drained reader
+type bodyFactory func() (io.Reader, error)
+
+// bytesBody returns a bodyFactory that replays the same bytes on every attempt
+func bytesBody(data []byte) bodyFactory {
+	return func() (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	}
+}
+
+// Response is the outcome of the attempt that ended a call
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string        // Decompressed body
+	Duration   time.Duration // Until the response headers arrived
+	Timings    Timings       // Where the time of the attempt went
+	RequestID  string        // Correlation ID of the call, empty when disabled
+	Redirects  []Redirect    // Redirects followed to reach this response, oldest first
+
+	BytesSent     int64 // Request body bytes written, after compression
+	BytesReceived int64 // Response body bytes read, before decompression
+
+	requestHeader http.Header // Headers sent, including credentials, for challenge authenticators
+	requestHost   string      // Host that answered, after redirects, for challenge authenticators
+}
+
+// responseBody returns the body of a possibly nil response
+func (r *Response) responseBody() string {
+	if r == nil {
+		return ""
+	}
+	return r.Body
+}
+
+// requestSpec describes one logical request as it flows through the pipeline
+type requestSpec struct {
+	method      string
+	description string
+	url         string
+	newBody     bodyFactory
+	payloadStr  string // Logged instead of the body
+	headers     map[string]string
+	requestID   string // Correlation ID of the call, empty when disabled
+	auth        Authenticator
+	check       responseCheck
+}
+
+// responseCheck turns a response into a final error that is not retried, such as a SOAP fault
+type responseCheck func(resp *Response) error
+
+type responseCheckKey struct{}
+
+// withResponseCheck returns a context whose attempts are inspected by check
+func withResponseCheck(ctx context.Context, check responseCheck) context.Context {
+	return context.WithValue(ctx, responseCheckKey{}, check)
+}
+
+// responseCheckFor returns the response check of a call, or nil
+func responseCheckFor(ctx context.Context) responseCheck {
+	check, _ := ctx.Value(responseCheckKey{}).(responseCheck)
+	return check
 }
 
 // Common request execution logic
-func executeRequest(method, description, urlStr string, body io.Reader, payloadStr string, headers map[string]string) (string, error) {
+func executeRequest(ctx context.Context, method, description, urlStr string, newBody bodyFactory, payloadStr string, headers map[string]string) (string, error) {
+	resp, err := executeRequestForResponse(ctx, method, description, urlStr, newBody, payloadStr, headers)
+	return resp.responseBody(), err
+}
+
+// executeRequestForResponse runs the request pipeline under ctx and returns the full response
+func executeRequestForResponse(ctx context.Context, method, description, urlStr string, newBody bodyFactory, payloadStr string, headers map[string]string) (*Response, error) {
 	ensureInitialized()
 
-	ctx, cancel := context.WithTimeout(context.Background(), config.BaseTimeout)
+	ctx, cancel := context.WithTimeout(ctx, config.BaseTimeout)
 	defer cancel()
 
-	return executeRequestWithRetry(ctx, method, description, urlStr, body, payloadStr, headers)
+	spec := &requestSpec{
+		method:      method,
+		description: description,
+		url:         urlStr,
+		newBody:     newBody,
+		payloadStr:  payloadStr,
+		headers:     headers,
+		requestID:   newRequestID(ctx, headers),
+		auth:        authenticatorFor(ctx),
+		check:       responseCheckFor(ctx),
+	}
+
+	// One client span per logical call, with a child span per attempt
+	ctx, span := startSpan(ctx, method, spec.spanAttributes()...)
+	resp, err := executeRequestWithRetry(ctx, spec)
+	endSpan(span, resp, err)
+
+	if resp != nil {
+		resp.RequestID = spec.requestID
+	}
+	return resp, withRequestIDError(spec.requestID, err)
 }
 
 // executeRequestWithRetry handles the retry logic
-func executeRequestWithRetry(ctx context.Context, method, description, urlStr string, body io.Reader, payloadStr string, headers map[string]string) (string, error) {
+func executeRequestWithRetry(ctx context.Context, spec *requestSpec) (*Response, error) {
 	var lastErr error
-	var responseBody string
+	var resp *Response
 
+	startTime := time.Now()
 	maxAttempts := config.RetryConfig.MaxRetries + 1 // +1 for the initial attempt
+	guards := newAttemptGuards(spec.description, spec.url)
+	hedge := newHedgePolicy(spec.method, spec.description)
+	budget := budgets.get(spec.url)
+	budget.recordRequest()
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
+	attempt := 0
+	for ; attempt < maxAttempts; attempt++ {
 		if attempt > 0 {
+			// Never start an attempt that cannot finish before the overall deadline
+			if !hasTimeForAttempt(ctx) {
+				logTaggedEntry(spec.requestID, "retry", fmt.Sprintf("Not enough time left for attempt %d/%d of %s", attempt+1, maxAttempts, spec.description), warningColor)
+				break
+			}
+
+			// Give up when retries across calls have used up the budget
+			if !budget.tryRetry() {
+				break
+			}
+
+			span := spanFromContext(ctx)
+			span.SetAttributes(Attribute{Key: AttrHTTPResendCount, Value: attempt})
+			span.AddEvent(EventRetry,
+				Attribute{Key: "attempt", Value: attempt + 1},
+				Attribute{Key: "delay", Value: config.RetryConfig.RetryDelay.String()},
+				Attribute{Key: "error", Value: lastErr.Error()},
+			)
+			metrics.retry(metrics.labels(spec))
+			runHook("OnRetry", config.Hooks.onRetry(), RetryEvent{
+				RequestInfo: spec.info(),
+				Attempt:     attempt + 1,
+				MaxAttempts: maxAttempts,
+				Delay:       config.RetryConfig.RetryDelay,
+				Err:         lastErr,
+			})
+
 			// Wait before retry
 			select {
 			case <-ctx.Done():
-				return "", ctx.Err()
+				lastErr = ctx.Err()
+				notifyGiveUp(spec, attempt, lastErr, startTime)
+				return nil, lastErr
 			case <-time.After(config.RetryConfig.RetryDelay):
 			}
 
-			// Reset body reader for retry
-			if seeker, ok := body.(io.Seeker); ok {
-				seeker.Seek(0, 0)
-			} else if body != nil {
-				// Recreate body from payloadStr for retry
-				body = strings.NewReader(payloadStr)
-			}
-
-			LogWarning("retry", fmt.Sprintf("Attempt %d/%d for %s", attempt+1, maxAttempts, description))
+			logTaggedEntry(spec.requestID, "retry", fmt.Sprintf("Attempt %d/%d for %s", attempt+1, maxAttempts, spec.description), warningColor)
 		}
 
-		responseBody, lastErr = executeRequestOnce(ctx, method, description, urlStr, body, payloadStr, headers)
+		if hedge != nil {
+			resp, lastErr = executeHedgedAttempt(ctx, hedge, guards, spec, attempt+1)
+		} else {
+			resp, lastErr = executeGuardedAttempt(ctx, guards, spec, attempt+1)
+		}
 
 		// If no error or context cancelled, return
 		if lastErr == nil || ctx.Err() != nil {
-			return responseBody, lastErr
+			if lastErr != nil {
+				notifyGiveUp(spec, attempt+1, lastErr, startTime)
+			}
+			return resp, lastErr
 		}
 
 		// Check if we should retry based on status code or error type
 		if !shouldRetry(lastErr) {
+			attempt++
 			break
 		}
 	}
 
-	return responseBody, lastErr
+	notifyGiveUp(spec, attempt, lastErr, startTime)
+	return resp, lastErr
+}
+
+// attemptGuards are the per-request protections shared by every attempt of a request
+type attemptGuards struct {
+	breaker  *circuitBreaker
+	bulkhead *adaptiveLimiter
+}
+
+// newAttemptGuards looks up the circuit breaker and bulkhead for a request
+func newAttemptGuards(description, urlStr string) attemptGuards {
+	return attemptGuards{
+		breaker:  breakers.get(description, urlStr),
+		bulkhead: bulkheads.get(urlStr),
+	}
+}
+
+// executeGuardedAttempt runs one attempt through the circuit breaker, rate limits and bulkhead
+func executeGuardedAttempt(ctx context.Context, guards attemptGuards, spec *requestSpec, attempt int) (*Response, error) {
+	ctx, span := startSpan(ctx, spec.method, append(spec.spanAttributes(), Attribute{Key: AttrHTTPResendCount, Value: attempt - 1})...)
+	labels := metrics.labels(spec)
+	metrics.started(labels)
+	startTime := time.Now()
+
+	resp, err := executeGuardedAttemptOnce(ctx, guards, spec, attempt)
+
+	metrics.finished(labels, resp, err, time.Since(startTime))
+	endSpan(span, resp, err)
+
+	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		event := ErrorEvent{RequestInfo: spec.info(), Attempt: attempt, Err: err}
+		if resp != nil {
+			event.StatusCode = resp.StatusCode
+		}
+		runHook("OnError", config.Hooks.onError(), event)
+	}
+	return resp, err
+}
+
+// executeGuardedAttemptOnce acquires the guards, sends the attempt and reports its outcome to the guards
+func executeGuardedAttemptOnce(ctx context.Context, guards attemptGuards, spec *requestSpec, attempt int) (*Response, error) {
+	// Fail fast while the circuit is open
+	if err := guards.breaker.allow(); err != nil {
+		logTaggedEntry(attemptRequestID(spec.requestID, attempt), "circuit-open", fmt.Sprintf("%s: %v", spec.description, err), errorColor)
+		spanFromContext(ctx).AddEvent(EventCircuitOpen, Attribute{Key: "circuit.key", Value: guards.breaker.key})
+		return nil, err
+	}
+
+	// Wait for rate and concurrency limits
+	releaseLimits, err := limiters.acquire(ctx, spec.url)
+	if err != nil {
+		guards.breaker.release()
+		logTaggedEntry(attemptRequestID(spec.requestID, attempt), "rate-limit", fmt.Sprintf("%s: %v", spec.description, err), errorColor)
+		return nil, err
+	}
+
+	// Take a slot in the upstream's adaptive bulkhead
+	if err := guards.bulkhead.acquire(ctx); err != nil {
+		releaseLimits()
+		guards.breaker.release()
+		logTaggedEntry(attemptRequestID(spec.requestID, attempt), "bulkhead", fmt.Sprintf("%s: %v", spec.description, err), errorColor)
+		return nil, err
+	}
+
+	// Build a fresh body for every attempt
+	var body io.Reader
+	if spec.newBody != nil {
+		body, err = spec.newBody()
+		if err != nil {
+			guards.bulkhead.cancel()
+			releaseLimits()
+			guards.breaker.release()
+			return nil, err
+		}
+	}
+
+	attemptCtx, cancelAttempt := attemptContext(ctx)
+	attemptStart := time.Now()
+	resp, err := executeRequestOnce(attemptCtx, spec, attempt, body)
+
+	// Answer an authentication challenge, such as an expired token, with one more request
+	if answerChallenge(spec.auth, resp) {
+		resp, err = resendAttempt(attemptCtx, spec, attempt)
+	}
+	latency := time.Since(attemptStart)
+	cancelAttempt()
+	releaseLimits()
+
+	// Attempts abandoned on purpose, such as losing hedges, say nothing about the upstream
+	if errors.Is(ctx.Err(), context.Canceled) {
+		guards.bulkhead.cancel()
+		guards.breaker.release()
+		return resp, err
+	}
+
+	failure := isCircuitFailure(resp, err)
+	guards.bulkhead.release(latency, failure)
+	guards.breaker.record(failure)
+	limiters.observe(spec.url, resp)
+
+	return resp, err
+}
+
+// resendAttempt sends an attempt again with a fresh body
+func resendAttempt(ctx context.Context, spec *requestSpec, attempt int) (*Response, error) {
+	var body io.Reader
+	if spec.newBody != nil {
+		var err error
+		body, err = spec.newBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return executeRequestOnce(ctx, spec, attempt, body)
+}
+
+// attemptContext bounds a single attempt by RetryConfig.AttemptTimeout within the overall deadline
+func attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if config.RetryConfig.AttemptTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, config.RetryConfig.AttemptTimeout)
+}
+
+// hasTimeForAttempt reports whether the retry delay and the minimum attempt time fit in the remaining
+// deadline. Without a MinAttemptTime, an attempt needs its whole AttemptTimeout.
+func hasTimeForAttempt(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return true
+	}
+
+	minAttemptTime := config.RetryConfig.MinAttemptTime
+	if minAttemptTime <= 0 {
+		minAttemptTime = config.RetryConfig.AttemptTimeout
+	}
+	return time.Until(deadline) > config.RetryConfig.RetryDelay+minAttemptTime
 }
 
 // executeRequestOnce executes a single request attempt
-func executeRequestOnce(ctx context.Context, method, description, urlStr string, body io.Reader, payloadStr string, headers map[string]string) (string, error) {
-	// Create the request
-	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
+func executeRequestOnce(ctx context.Context, spec *requestSpec, attempt int, body io.Reader) (*Response, error) {
+	// Streamed bodies such as multipart forms hold open files and a writer goroutine. The transport
+	// closes the body once the request is sent, so close it on every path that fails before that.
+	delivered := false
+	if closer, ok := body.(io.Closer); ok {
+		defer func() {
+			if !delivered {
+				closer.Close()
+			}
+		}()
+	}
+
+	// Compress the body when configured
+	body, headers, requestFields, err := compressRequestBody(body, spec.headers)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// Create the request, recording where its time goes
+	ctx, timings := withTimingTrace(ctx)
+	req, err := http.NewRequestWithContext(ctx, spec.method, spec.url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the bytes actually written, keeping the Content-Length and GetBody set from the body
+	var sent *countingReader
+	if req.Body != nil && req.Body != http.NoBody {
+		sent = &countingReader{reader: req.Body}
+		req.Body = sent
 	}
 
 	// Add headers
@@ -202,45 +549,148 @@ func executeRequestOnce(ctx context.Context, method, description, urlStr string,
 		req.Header.Add(key, value)
 	}
 
+	// Advertise the registered encodings so responses are decoded by this package instead of the transport
+	decompress := config.CompressionConfig != nil && config.CompressionConfig.DecompressResponses
+	if decompress && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncodingHeader())
+	}
+
+	// Propagate the attempt span, e.g. as a W3C traceparent header
+	injectTraceContext(ctx, req.Header)
+
+	// Correlate the attempt with the upstream's logs
+	if spec.requestID != "" {
+		req.Header.Set(requestIDHeader(), attemptRequestID(spec.requestID, attempt))
+	}
+
 	// Log the request details
-	logRequest(method, urlStr, description, headers, payloadStr)
+	logRequest(attemptRequestID(spec.requestID, attempt), spec.method, spec.url, spec.description, headers, spec.payloadStr, requestFields...)
+	runHook("OnRequest", config.Hooks.onRequest(), RequestEvent{
+		RequestInfo: spec.info(),
+		Attempt:     attempt,
+		Header:      req.Header.Clone(), // A copy, so a hook keeping it never sees the credentials added below
+	})
+
+	// Add credentials last so they never reach logs or hooks
+	if err := authenticate(spec.auth, req); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
 
 	// Perform the request
 	startTime := time.Now()
-	resp, err := httpClient.Do(req)
+	timings.begin()
+	resp, err := roundTripper.RoundTrip(req)
 	duration := time.Since(startTime)
+	delivered = err == nil
 
 	if err != nil {
-		LogError("request-error", fmt.Sprintf("%s: %v", description, err))
-		return "", err
+		err = redactURLError(err, spec.url)
+		logTaggedEntry(attemptRequestID(spec.requestID, attempt), "request-error", fmt.Sprintf("%s: %v", spec.description, err), errorColor)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read the response
+	readStart := time.Now()
 	responseBody, err := ReadResponseBody(resp)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	timings.bodyRead(time.Since(readStart))
+
+	bytesReceived := int64(len(responseBody))
+
+	// Decode compressed responses
+	var responseFields []logField
+	if decompress {
+		responseBody, responseFields, err = decompressResponseBody(resp.Header, responseBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	redirects := redirectChain(resp)
+	if len(redirects) > 0 {
+		responseFields = append(responseFields, logField{property: "redirects", value: formatRedirects(redirects)})
+	}
+
+	if config.LoggingConfig.LogTimings {
+		responseFields = append(responseFields, logField{property: "timings", value: timings.result()})
 	}
 
 	// Log the response details with duration
-	logResponseWithDuration(description, responseBody, resp.StatusCode, duration)
+	logResponseWithDuration(attemptRequestID(spec.requestID, attempt), spec.description, loggableBody(resp.Header.Get("Content-Type"), responseBody), resp.StatusCode, duration, responseFields...)
+	runHook("OnResponse", config.Hooks.onResponse(), ResponseEvent{
+		RequestInfo: spec.info(),
+		Attempt:     attempt,
+		StatusCode:  resp.StatusCode,
+		Header:      resp.Header,
+		Duration:    duration,
+		BodySize:    len(responseBody),
+	})
+
+	result := &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       responseBody,
+		Duration:   duration,
+		Timings:    timings.result(),
+		Redirects:  redirects,
+
+		BytesSent:     sent.count(),
+		BytesReceived: bytesReceived,
+
+		requestHeader: req.Header,
+		requestHost:   resp.Request.URL.Host,
+	}
+
+	// Let the caller classify the response, e.g. a SOAP fault sent with a 500 status
+	if spec.check != nil {
+		if err := spec.check(result); err != nil {
+			return result, err
+		}
+	}
 
 	// Check for non-2xx status codes
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return responseBody, fmt.Errorf("received non-2xx response code: %d", resp.StatusCode)
+		return result, fmt.Errorf("received non-2xx response code: %d", resp.StatusCode)
 	}
 
-	return responseBody, nil
+	return result, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.ReadCloser
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.reader.Close()
+}
+
+// count returns the bytes read so far, 0 for a nil reader
+func (c *countingReader) count() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.n
 }
 
 // logResponseWithDuration logs response with duration info
-func logResponseWithDuration(description string, response string, statusCode int, duration time.Duration) {
+func logResponseWithDuration(requestID, description string, response string, statusCode int, duration time.Duration, extras ...logField) {
 	if !config.LoggingConfig.Enabled {
 		return
 	}
 
 	logSeparator()
-	logColoredEntry("incoming-response", description, warningColor)
+	logTaggedEntry(requestID, "incoming-response", description, warningColor)
 
 	// Color status code based on value
 	if statusCode != 0 {
@@ -255,14 +705,15 @@ func logResponseWithDuration(description string, response string, statusCode int
 		default:
 			statusColorFunc = statusColor
 		}
-		logColoredEntry("status", statusCode, statusColorFunc)
+		logTaggedEntry(requestID, "status", statusCode, statusColorFunc)
 	}
 
-	logColoredEntry("duration", duration.String(), methodColor)
+	logTaggedEntry(requestID, "duration", duration.String(), methodColor)
+	logFields(requestID, extras)
 
 	if config.LoggingConfig.LogResponseBody {
 		formattedBody := formatBody(response)
-		logColoredEntry("response", formattedBody, bodyColor)
+		logTaggedEntry(requestID, "response", formattedBody, bodyColor)
 	}
 
 	logSeparator()
@@ -274,6 +725,27 @@ func shouldRetry(err error) bool {
 		return false
 	}
 
+	// Requests rejected by a client-side guard were never sent and fail immediately
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrConcurrencyLimited) {
+		return false
+	}
+
+	// Refused redirects would be refused again
+	if isRefusedRedirect(err) {
+		return false
+	}
+
+	// SOAP faults are answers from the service; resending the request gets the same fault
+	var fault *SOAPFault
+	if errors.As(err, &fault) {
+		return false
+	}
+
+	// Attempts cut off by AttemptTimeout are retried; the overall deadline is checked by the caller
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
 	// Check if it's a status code error that should be retried
 	errStr := err.Error()
 	for _, statusCode := range config.RetryConfig.RetryOnStatus {
@@ -289,29 +761,42 @@ func shouldRetry(err error) bool {
 		strings.Contains(errStr, "EOF")
 }
 
+// MakeRequestWithContext sends a request with any HTTP method under ctx, so that cancellation and
+// the caller's trace are carried into the call. Like MakeGETRequest and MakePOSTRequest, the payload
+// becomes query parameters for GET, DELETE, HEAD and OPTIONS and the body otherwise.
+func MakeRequestWithContext(ctx context.Context, method, description, url string, payload map[string]interface{}, headers map[string]string) (string, error) {
+	return makeRequest(ctx, strings.ToUpper(method), description, url, payload, headers)
+}
+
+// MakeRequestForResponse is MakeRequestWithContext returning the full response, including its
+// status code, headers and timings. The response is also returned with non-2xx errors.
+func MakeRequestForResponse(ctx context.Context, method, description, url string, payload map[string]interface{}, headers map[string]string) (*Response, error) {
+	return makeRequestForResponse(ctx, strings.ToUpper(method), description, url, payload, headers)
+}
+
 // Update the public functions to use the common handler
 func MakeGETRequest(description, baseURL string, queryParams map[string]string, headers map[string]string) (string, error) {
 	payload := make(map[string]interface{})
 	for k, v := range queryParams {
 		payload[k] = v
 	}
-	return makeRequest(methodGET, description, baseURL, payload, headers)
+	return makeRequest(context.Background(), methodGET, description, baseURL, payload, headers)
 }
 
 func MakePOSTRequest(description, url string, payload map[string]interface{}, headers map[string]string) (string, error) {
-	return makeRequest(methodPOST, description, url, payload, headers)
+	return makeRequest(context.Background(), methodPOST, description, url, payload, headers)
 }
 
 func MakePOSTRequestWithString(description, url string, payload string, headers map[string]string) (string, error) {
-	return makeRequestWithString(methodPOST, description, url, payload, headers)
+	return makeRequestWithString(context.Background(), methodPOST, description, url, payload, headers)
 }
 
 func MakePUTRequest(description, url string, payload map[string]interface{}, headers map[string]string) (string, error) {
-	return makeRequest(methodPUT, description, url, payload, headers)
+	return makeRequest(context.Background(), methodPUT, description, url, payload, headers)
 }
 
 func MakePUTRequestWithString(description, url string, payload string, headers map[string]string) (string, error) {
-	return makeRequestWithString(methodPUT, description, url, payload, headers)
+	return makeRequestWithString(context.Background(), methodPUT, description, url, payload, headers)
 }
 
 func MakeDELETERequest(description, url string, queryParams map[string]string, headers map[string]string) (string, error) {
@@ -319,15 +804,15 @@ func MakeDELETERequest(description, url string, queryParams map[string]string, h
 	for k, v := range queryParams {
 		payload[k] = v
 	}
-	return makeRequest(methodDELETE, description, url, payload, headers)
+	return makeRequest(context.Background(), methodDELETE, description, url, payload, headers)
 }
 
 func MakePATCHRequest(description, url string, payload map[string]interface{}, headers map[string]string) (string, error) {
-	return makeRequest(methodPATCH, description, url, payload, headers)
+	return makeRequest(context.Background(), methodPATCH, description, url, payload, headers)
 }
 
 func MakePATCHRequestWithString(description, url string, payload string, headers map[string]string) (string, error) {
-	return makeRequestWithString(methodPATCH, description, url, payload, headers)
+	return makeRequestWithString(context.Background(), methodPATCH, description, url, payload, headers)
 }
 
 func MakeHEADRequest(description, url string, queryParams map[string]string, headers map[string]string) (string, error) {
@@ -335,7 +820,7 @@ func MakeHEADRequest(description, url string, queryParams map[string]string, hea
 	for k, v := range queryParams {
 		payload[k] = v
 	}
-	return makeRequest(methodHEAD, description, url, payload, headers)
+	return makeRequest(context.Background(), methodHEAD, description, url, payload, headers)
 }
 
 func MakeOPTIONSRequest(description, url string, queryParams map[string]string, headers map[string]string) (string, error) {
@@ -343,11 +828,16 @@ func MakeOPTIONSRequest(description, url string, queryParams map[string]string,
 	for k, v := range queryParams {
 		payload[k] = v
 	}
-	return makeRequest(methodOPTIONS, description, url, payload, headers)
+	return makeRequest(context.Background(), methodOPTIONS, description, url, payload, headers)
 }
 
 // MakeXMLPostRequest sends raw XML/SOAP payload without JSON encoding or quoting
 func MakeXMLPostRequest(description, urlStr string, xmlPayload string, headers map[string]string) (string, error) {
+	return makeXMLRequest(context.Background(), methodPOST, description, urlStr, bytesBody([]byte(xmlPayload)), xmlPayload, headers)
+}
+
+// makeXMLRequest sends a raw XML body, logging loggedPayload instead of the real one
+func makeXMLRequest(ctx context.Context, method, description, urlStr string, body bodyFactory, loggedPayload string, headers map[string]string) (string, error) {
 	ensureInitialized()
 
 	// Ensure Content-Type for SOAP/XML
@@ -358,16 +848,14 @@ func MakeXMLPostRequest(description, urlStr string, xmlPayload string, headers m
 		headers["Content-Type"] = "text/xml; charset=UTF-8"
 	}
 
-	// Raw XML body
-	body := bytes.NewBuffer([]byte(xmlPayload))
-
 	// Run using the common execution pipeline (retry + logs)
 	return executeRequest(
-		methodPOST,
+		ctx,
+		method,
 		description,
 		urlStr,
 		body,
-		xmlPayload, // logged raw XML, not wrapped
+		loggedPayload, // logged raw XML, not wrapped
 		headers,
 	)
 }
@@ -0,0 +1,82 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestConfig returns a quiet configuration for tests
+func newTestConfig() *Config {
+	cfg := NewConfig(5 * time.Second)
+	cfg.LoggingConfig.Enabled = false
+	return cfg
+}
+
+// initTestConfig initializes the package with cfg for the duration of a test
+func initTestConfig(t *testing.T, cfg *Config) {
+	t.Helper()
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		isInitialized = false
+	})
+}
+
+// captureOutput returns what fn logs to stdout
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-output
+}
+
+func TestRetriesStopWhenAnAttemptCannotFinish(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.BaseTimeout = 400 * time.Millisecond
+	cfg.RetryConfig.MaxRetries = 10
+	cfg.RetryConfig.RetryDelay = 10 * time.Millisecond
+	cfg.RetryConfig.AttemptTimeout = 100 * time.Millisecond
+	initTestConfig(t, cfg)
+
+	// Attempts end at about 100ms, 210ms and 320ms; a fourth would need 110ms of the 80ms left
+	_, err := MakeGETRequest("Get Users", server.URL, nil, nil)
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("%d attempts started, want 3", got)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the attempt timeout", err)
+	}
+}
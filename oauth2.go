@@ -0,0 +1,232 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTokenExpiryDelta is used when OAuth2Config.ExpiryDelta is not set
+const defaultTokenExpiryDelta = 30 * time.Second
+
+// defaultTokenTimeout is used when OAuth2Config.Timeout is not set
+const defaultTokenTimeout = 30 * time.Second
+
+// OAuth2Config holds the client credentials used to fetch OAuth2 access tokens
+type OAuth2Config struct {
+	TokenURL       string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+	EndpointParams map[string]string // Extra form values sent to the token endpoint, e.g. audience
+	AuthInBody     bool              // Send the client credentials in the form instead of a Basic header
+	ExpiryDelta    time.Duration     // Refresh tokens this long before they expire, default 30s
+	HTTPClient     *http.Client      // Client used for the token endpoint, default a dedicated client
+	Timeout        time.Duration     // Timeout of a token request, default 30s
+}
+
+// OAuth2Authenticator authenticates requests with access tokens obtained through the OAuth2
+// client credentials grant. Tokens are cached until shortly before they expire, concurrent
+// requests share a single refresh, and a 401 response discards the token and resends the
+// request once with a new one.
+type OAuth2Authenticator struct {
+	cfg     *OAuth2Config
+	client  *http.Client
+	timeout time.Duration
+
+	mu         sync.Mutex
+	token      string
+	expiry     time.Time     // Zero when the token does not expire
+	refreshing chan struct{} // Closed when the refresh in flight completes
+	refreshErr error
+}
+
+// NewOAuth2Authenticator creates an authenticator for the given client credentials. Tokens are
+// fetched with their own client, so they can be requested before Init.
+func NewOAuth2Authenticator(cfg *OAuth2Config) *OAuth2Authenticator {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTokenTimeout
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: timeout}
+	}
+	return &OAuth2Authenticator{cfg: cfg, client: client, timeout: timeout}
+}
+
+// tokenResponse is the token endpoint's answer, RFC 6749 section 5
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Authenticate implements Authenticator
+func (a *OAuth2Authenticator) Authenticate(req *http.Request) error {
+	token, err := a.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Challenge implements ChallengeAuthenticator: a 401 means the token was revoked or expired early
+func (a *OAuth2Authenticator) Challenge(resp *Response) bool {
+	rejected := strings.TrimPrefix(resp.requestHeader.Get("Authorization"), "Bearer ")
+	a.invalidate(rejected)
+	return true
+}
+
+// Invalidate discards the cached token so the next request fetches a new one
+func (a *OAuth2Authenticator) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// invalidate discards the cached token only if it is the rejected one. Concurrent requests
+// rejected with the same token then share one refresh, and a token fetched after the rejected
+// one was sent is kept.
+func (a *OAuth2Authenticator) invalidate(rejected string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == rejected {
+		a.token = ""
+	}
+}
+
+// Token returns a valid access token, fetching one when none is cached or it is about to expire
+func (a *OAuth2Authenticator) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	if a.valid() {
+		token := a.token
+		a.mu.Unlock()
+		return token, nil
+	}
+
+	// Join the refresh in flight, or start one
+	refreshing := a.refreshing
+	if refreshing == nil {
+		refreshing = make(chan struct{})
+		a.refreshing = refreshing
+		go a.refresh(refreshing)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-refreshing:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.refreshErr != nil {
+		return "", a.refreshErr
+	}
+	return a.token, nil
+}
+
+// valid reports whether the cached token can still be used. Callers hold a.mu.
+func (a *OAuth2Authenticator) valid() bool {
+	if a.token == "" {
+		return false
+	}
+	return a.expiry.IsZero() || time.Now().Before(a.expiry)
+}
+
+// refresh fetches a new token and wakes up the requests waiting for it. It runs detached from
+// the request that started it so that a cancelled caller does not fail the others.
+func (a *OAuth2Authenticator) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	token, expiresIn, err := a.fetch(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	defer close(done)
+
+	a.refreshing = nil
+	a.refreshErr = err
+	if err != nil {
+		LogError("oauth2", fmt.Sprintf("%s: %v", a.cfg.TokenURL, err))
+		return
+	}
+
+	a.token = token
+	a.expiry = time.Time{}
+	if expiresIn > 0 {
+		// Refresh early, but never use less than half of the token's lifetime
+		delta := a.cfg.ExpiryDelta
+		if delta <= 0 {
+			delta = defaultTokenExpiryDelta
+		}
+		if delta > expiresIn/2 {
+			delta = expiresIn / 2
+		}
+		a.expiry = time.Now().Add(expiresIn - delta)
+	}
+	LogInfo("oauth2", fmt.Sprintf("%s: fetched token, expires in %s", a.cfg.TokenURL, expiresIn))
+}
+
+// fetch requests a token from the token endpoint
+func (a *OAuth2Authenticator) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.cfg.Scopes, " "))
+	}
+	for key, value := range a.cfg.EndpointParams {
+		form.Set(key, value)
+	}
+	if a.cfg.AuthInBody {
+		form.Set("client_id", a.cfg.ClientID)
+		form.Set("client_secret", a.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, methodPOST, a.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !a.cfg.AuthInBody {
+		req.SetBasicAuth(url.QueryEscape(a.cfg.ClientID), url.QueryEscape(a.cfg.ClientSecret))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", redactURLError(err, a.cfg.TokenURL))
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil && resp.StatusCode < 300 {
+		return "", 0, fmt.Errorf("invalid token response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if token.Error != "" {
+			return "", 0, fmt.Errorf("token request failed with status %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+	if token.AccessToken == "" {
+		return "", 0, errors.New("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type %q", token.TokenType)
+	}
+
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
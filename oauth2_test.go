@@ -0,0 +1,138 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer serves tokens "token-1", "token-2", ... and counts the fetches
+func newTokenServer(t *testing.T, fetches *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := fetches.Add(1)
+		time.Sleep(20 * time.Millisecond) // Give concurrent callers time to pile up
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOAuth2ConcurrentChallengesShareOneRefresh(t *testing.T) {
+	const callers = 8
+
+	var fetches atomic.Int32
+	tokenServer := newTokenServer(t, &fetches)
+
+	// Reject the first token once every caller has sent it, so all of them are challenged together
+	var arrived atomic.Int32
+	allArrived := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			if arrived.Add(1) == callers {
+				close(allArrived)
+			}
+			select {
+			case <-allArrived:
+			case <-time.After(2 * time.Second):
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	auth := NewOAuth2Authenticator(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"})
+	initTestConfig(t, newTestConfig().WithAuthenticator(auth))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := MakeGETRequest("Get Orders", server.URL, nil, nil); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("request failed: %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("token fetched %d times, want the initial fetch and one refresh", got)
+	}
+}
+
+func TestOAuth2ChallengeKeepsNewerToken(t *testing.T) {
+	var fetches atomic.Int32
+	tokenServer := newTokenServer(t, &fetches)
+	initTestConfig(t, newTestConfig())
+
+	auth := NewOAuth2Authenticator(&OAuth2Config{TokenURL: tokenServer.URL})
+	auth.mu.Lock()
+	auth.token = "token-2"
+	auth.mu.Unlock()
+
+	// A 401 for a token that was already replaced must not discard the replacement
+	stale := &Response{StatusCode: http.StatusUnauthorized, requestHeader: http.Header{"Authorization": {"Bearer token-1"}}}
+	if !auth.Challenge(stale) {
+		t.Fatal("expected the challenge to be answered")
+	}
+	if auth.token != "token-2" {
+		t.Fatalf("cached token = %q after a stale rejection, want token-2", auth.token)
+	}
+
+	current := &Response{StatusCode: http.StatusUnauthorized, requestHeader: http.Header{"Authorization": {"Bearer token-2"}}}
+	auth.Challenge(current)
+	if auth.token != "" {
+		t.Fatalf("cached token = %q after its rejection, want it discarded", auth.token)
+	}
+	if fetches.Load() != 0 {
+		t.Fatal("a challenge must not fetch a token by itself")
+	}
+}
+
+func TestOAuth2TokenBeforeInit(t *testing.T) {
+	// Simulate a process where Init has not run and no request has been made yet
+	savedConfig, savedClient := config, httpClient
+	config, httpClient = nil, nil
+	t.Cleanup(func() { config, httpClient = savedConfig, savedClient })
+
+	var fetches atomic.Int32
+	tokenServer := newTokenServer(t, &fetches)
+
+	auth := NewOAuth2Authenticator(&OAuth2Config{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"})
+	token, err := auth.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if token != "token-1" {
+		t.Fatalf("token = %q, want token-1", token)
+	}
+}
+
+func TestOAuth2TokenRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	auth := NewOAuth2Authenticator(&OAuth2Config{TokenURL: slow.URL, Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := auth.Token(context.Background()); err == nil {
+		t.Fatal("expected the token request to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("token request took %s, want it bounded by the 50ms timeout", elapsed)
+	}
+}
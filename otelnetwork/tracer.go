@@ -0,0 +1,123 @@
+// Package otelnetwork connects the network package to OpenTelemetry tracing
+package otelnetwork
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	network "github.com/Defolt-Labs/RestCallPackage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this package
+const instrumentationName = "github.com/Defolt-Labs/RestCallPackage/otelnetwork"
+
+// Config holds the OpenTelemetry providers used by the tracer. Every field is optional.
+type Config struct {
+	TracerProvider trace.TracerProvider          // Default: the global provider
+	Propagator     propagation.TextMapPropagator // Default: the global propagator, or W3C trace context when none is set
+}
+
+// Tracer implements network.Tracer with OpenTelemetry
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a tracer; cfg may be nil to use the global providers
+func NewTracer(cfg *Config) *Tracer {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	provider := cfg.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: cfg.Propagator,
+	}
+}
+
+// Start implements network.Tracer, starting a client span as a child of the span in ctx
+func (t *Tracer) Start(ctx context.Context, name string, attributes ...network.Attribute) (context.Context, network.Span) {
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(toKeyValues(attributes)...),
+	)
+	return ctx, Span{span: span}
+}
+
+// Inject implements network.Tracer, writing the trace context of ctx into header
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.textMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// textMapPropagator returns the configured propagator. The global one is looked up on every call
+// because applications often set it after creating clients; until then the W3C traceparent is sent.
+func (t *Tracer) textMapPropagator() propagation.TextMapPropagator {
+	if t.propagator != nil {
+		return t.propagator
+	}
+	if global := otel.GetTextMapPropagator(); len(global.Fields()) > 0 {
+		return global
+	}
+	return propagation.TraceContext{}
+}
+
+// Span implements network.Span with an OpenTelemetry span
+type Span struct {
+	span trace.Span
+}
+
+// SetAttributes implements network.Span
+func (s Span) SetAttributes(attributes ...network.Attribute) {
+	s.span.SetAttributes(toKeyValues(attributes)...)
+}
+
+// AddEvent implements network.Span
+func (s Span) AddEvent(name string, attributes ...network.Attribute) {
+	s.span.AddEvent(name, trace.WithAttributes(toKeyValues(attributes)...))
+}
+
+// SetError implements network.Span, recording err and marking the span as failed
+func (s Span) SetError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End implements network.Span
+func (s Span) End() {
+	s.span.End()
+}
+
+// toKeyValues converts attributes, keeping numbers and booleans typed
+func toKeyValues(attributes []network.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		switch v := a.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(a.Key, v))
+		case int:
+			kvs = append(kvs, attribute.Int(a.Key, v))
+		case int64:
+			kvs = append(kvs, attribute.Int64(a.Key, v))
+		case float64:
+			kvs = append(kvs, attribute.Float64(a.Key, v))
+		case bool:
+			kvs = append(kvs, attribute.Bool(a.Key, v))
+		case time.Duration:
+			kvs = append(kvs, attribute.String(a.Key, v.String()))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
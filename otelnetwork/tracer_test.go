@@ -0,0 +1,76 @@
+package otelnetwork
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	network "github.com/Defolt-Labs/RestCallPackage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracerRecordsSpansAndPropagates(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cfg := network.NewConfig(5 * time.Second).WithTracer(NewTracer(&Config{TracerProvider: provider}))
+	cfg.LoggingConfig.Enabled = false
+	if err := network.Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	if _, err := network.MakeRequestWithContext(ctx, "GET", "Get User", server.URL+"/users/1", nil, nil); err == nil {
+		t.Fatal("expected a 404 error")
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected attempt, call and parent spans, got %d", len(spans))
+	}
+	attempt, call := spans[0], spans[1]
+
+	if call.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("the call span is not a child of the caller's span")
+	}
+	if attempt.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Fatal("the attempt span is not a child of the call span")
+	}
+	if call.SpanKind() != trace.SpanKindClient || call.Name() != "GET" {
+		t.Fatalf("call span is %s %q, want a client span named GET", call.SpanKind(), call.Name())
+	}
+	if call.Status().Code != codes.Error {
+		t.Fatalf("call span status = %v, want Error", call.Status().Code)
+	}
+
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range call.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	if got := attributes[network.AttrHTTPStatusCode]; got.AsInt64() != 404 {
+		t.Fatalf("status code attribute = %v, want 404", got.Emit())
+	}
+	if got := attributes[network.AttrErrorType]; got.AsString() != "404" {
+		t.Fatalf("error type attribute = %q, want 404", got.AsString())
+	}
+
+	// The upstream continues the trace from the attempt span
+	want := "00-" + attempt.SpanContext().TraceID().String() + "-" + attempt.SpanContext().SpanID().String() + "-01"
+	if traceparent != want {
+		t.Fatalf("traceparent = %q, want %q", traceparent, want)
+	}
+}
@@ -0,0 +1,158 @@
+// Package promnetwork connects the network package to Prometheus metrics
+package promnetwork
+
+import (
+	"time"
+
+	network "github.com/Defolt-Labs/RestCallPackage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Config holds the Prometheus settings of the metrics. Every field is optional.
+type Config struct {
+	Registerer prometheus.Registerer // Default: prometheus.DefaultRegisterer
+	Namespace  string                // Prefix of the metric names, e.g. "myapp"
+	Buckets    []float64             // Latency histogram buckets in seconds. Default: prometheus.DefBuckets
+}
+
+// Metrics implements network.Metrics with Prometheus collectors
+type Metrics struct {
+	requests *prometheus.CounterVec   // method, host, route, status_class, error
+	latency  *prometheus.HistogramVec // method, host, route
+	inFlight *prometheus.GaugeVec     // method, host, route
+	retries  *prometheus.CounterVec   // method, host, route
+	hedges   *prometheus.CounterVec   // method, host, route
+	bytes    *prometheus.CounterVec   // method, host, route, direction
+	phases   *prometheus.HistogramVec // method, host, route, phase
+	reused   *prometheus.CounterVec   // method, host, route
+}
+
+// labelNames are the labels shared by every metric
+var labelNames = []string{"method", "host", "route"}
+
+// NewMetrics creates the collectors and registers them; cfg may be nil to use the defaults
+func NewMetrics(cfg *Config) (*Metrics, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	registerer := cfg.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	buckets := cfg.Buckets
+	if buckets == nil {
+		buckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "requests_total",
+			Help:      "Outbound request attempts by status class and error kind.",
+		}, append(labelNames, "status_class", "error")),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of outbound request attempts.",
+			Buckets:   buckets,
+		}, labelNames),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "requests_in_flight",
+			Help:      "Outbound request attempts in progress.",
+		}, labelNames),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "retries_total",
+			Help:      "Retries of outbound requests.",
+		}, labelNames),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "hedges_total",
+			Help:      "Hedged copies of outbound requests.",
+		}, labelNames),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "bytes_total",
+			Help:      "Bytes sent and received by outbound requests.",
+		}, append(labelNames, "direction")),
+		phases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "request_phase_duration_seconds",
+			Help:      "Duration of the dns, connect, tls and ttfb phases of outbound request attempts.",
+			Buckets:   buckets,
+		}, append(labelNames, "phase")),
+		reused: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Subsystem: "http_client",
+			Name:      "connections_reused_total",
+			Help:      "Outbound request attempts sent on a reused connection.",
+		}, labelNames),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.latency, m.inFlight, m.retries, m.hedges, m.bytes, m.phases, m.reused} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// RequestStarted implements network.Metrics
+func (m *Metrics) RequestStarted(l network.MetricLabels) {
+	m.inFlight.WithLabelValues(l.Method, l.Host, l.Route).Inc()
+}
+
+// RequestFinished implements network.Metrics
+func (m *Metrics) RequestFinished(l network.MetricLabels, r network.MetricResult) {
+	m.inFlight.WithLabelValues(l.Method, l.Host, l.Route).Dec()
+	m.requests.WithLabelValues(l.Method, l.Host, l.Route, r.StatusClass, r.ErrorKind).Inc()
+	m.latency.WithLabelValues(l.Method, l.Host, l.Route).Observe(r.Duration.Seconds())
+	m.bytes.WithLabelValues(l.Method, l.Host, l.Route, "sent").Add(float64(r.BytesSent))
+	m.bytes.WithLabelValues(l.Method, l.Host, l.Route, "received").Add(float64(r.BytesReceived))
+	m.observeTimings(l, r)
+}
+
+// observeTimings records the connection phases of an attempt. Phases that did not happen, such as
+// DNS on a reused connection, are skipped rather than observed as zero.
+func (m *Metrics) observeTimings(l network.MetricLabels, r network.MetricResult) {
+	if r.StatusClass == "" {
+		return
+	}
+
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"dns", r.Timings.DNSLookup},
+		{"connect", r.Timings.Connect},
+		{"tls", r.Timings.TLSHandshake},
+		{"ttfb", r.Timings.TimeToFirstByte},
+	}
+	for _, phase := range phases {
+		if phase.duration > 0 {
+			m.phases.WithLabelValues(l.Method, l.Host, l.Route, phase.name).Observe(phase.duration.Seconds())
+		}
+	}
+	if r.Timings.ConnectionReused {
+		m.reused.WithLabelValues(l.Method, l.Host, l.Route).Inc()
+	}
+}
+
+// Retry implements network.Metrics
+func (m *Metrics) Retry(l network.MetricLabels) {
+	m.retries.WithLabelValues(l.Method, l.Host, l.Route).Inc()
+}
+
+// Hedge implements network.Metrics
+func (m *Metrics) Hedge(l network.MetricLabels) {
+	m.hedges.WithLabelValues(l.Method, l.Host, l.Route).Inc()
+}
@@ -0,0 +1,142 @@
+package promnetwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	network "github.com/Defolt-Labs/RestCallPackage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsRecordsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(&Config{Registerer: registry, Namespace: "test"})
+	if err != nil {
+		t.Fatalf("NewMetrics: %v", err)
+	}
+
+	cfg := network.NewConfig(5 * time.Second).WithMetrics(&network.MetricsConfig{
+		Metrics: metrics,
+		Routes:  []string{"/users/{id}"},
+	})
+	cfg.LoggingConfig.Enabled = false
+	if err := network.Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if _, err := network.MakeGETRequest("Get User", server.URL+"/users/"+id, nil, nil); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	expected := `
+# HELP test_http_client_requests_total Outbound request attempts by status class and error kind.
+# TYPE test_http_client_requests_total counter
+test_http_client_requests_total{error="",host="` + host + `",method="GET",route="/users/{id}",status_class="2xx"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "test_http_client_requests_total"); err != nil {
+		t.Fatal(err)
+	}
+
+	inFlight := metrics.inFlight.WithLabelValues("GET", host, "/users/{id}")
+	if got := testutil.ToFloat64(inFlight); got != 0 {
+		t.Fatalf("in-flight gauge = %v after the calls ended, want 0", got)
+	}
+	received := metrics.bytes.WithLabelValues("GET", host, "/users/{id}", "received")
+	if got := testutil.ToFloat64(received); got != 4 {
+		t.Fatalf("received bytes = %v, want 4", got)
+	}
+	if count := testutil.CollectAndCount(metrics.latency); count != 1 {
+		t.Fatalf("latency series = %d, want 1", count)
+	}
+
+	// The second call reuses the first call's connection, so only the first one connects
+	if got := testutil.ToFloat64(metrics.reused.WithLabelValues("GET", host, "/users/{id}")); got != 1 {
+		t.Fatalf("reused connections = %v, want 1", got)
+	}
+	if got := phaseCount(t, registry, "connect"); got != 1 {
+		t.Fatalf("connect observations = %d, want 1", got)
+	}
+	if got := phaseCount(t, registry, "ttfb"); got != 2 {
+		t.Fatalf("ttfb observations = %d, want 2", got)
+	}
+}
+
+func TestMetricsSkipsPhasesThatDidNotHappen(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(&Config{Registerer: registry, Namespace: "test"})
+	if err != nil {
+		t.Fatalf("NewMetrics: %v", err)
+	}
+
+	labels := network.MetricLabels{Method: "GET", Host: "api.example.com", Route: "Get User"}
+	metrics.RequestFinished(labels, network.MetricResult{
+		StatusClass: "2xx",
+		Timings: network.Timings{
+			DNSLookup:       2 * time.Millisecond,
+			Connect:         3 * time.Millisecond,
+			TLSHandshake:    10 * time.Millisecond,
+			TimeToFirstByte: 40 * time.Millisecond,
+		},
+	})
+	metrics.RequestFinished(labels, network.MetricResult{
+		StatusClass: "2xx",
+		Timings:     network.Timings{TimeToFirstByte: 20 * time.Millisecond, ConnectionReused: true},
+	})
+	// No response, so no phases
+	metrics.RequestFinished(labels, network.MetricResult{ErrorKind: "timeout"})
+
+	for phase, want := range map[string]uint64{"dns": 1, "connect": 1, "tls": 1, "ttfb": 2} {
+		if got := phaseCount(t, registry, phase); got != want {
+			t.Errorf("%s observations = %d, want %d", phase, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.reused.WithLabelValues("GET", "api.example.com", "Get User")); got != 1 {
+		t.Fatalf("reused connections = %v, want 1", got)
+	}
+}
+
+// phaseCount returns the number of observations of a phase histogram across all label values
+func phaseCount(t *testing.T, registry *prometheus.Registry, phase string) uint64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	var count uint64
+	for _, family := range families {
+		if family.GetName() != "test_http_client_request_phase_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "phase" && label.GetValue() == phase {
+					count += metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return count
+}
+
+func TestNewMetricsReportsDuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := NewMetrics(&Config{Registerer: registry}); err != nil {
+		t.Fatalf("NewMetrics: %v", err)
+	}
+	if _, err := NewMetrics(&Config{Registerer: registry}); err == nil {
+		t.Fatal("expected registering the collectors twice to fail")
+	}
+}
@@ -0,0 +1,338 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by fail-fast rate limiters when a request would have to wait
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket, or nil when no rate is configured
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+// When failFast is set and a wait would be needed, no token is taken and ok is false.
+func (b *tokenBucket) reserve(failFast bool) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if failFast {
+		return 0, false
+	}
+
+	// Go into debt; the caller waits until the debt is repaid
+	b.tokens--
+	return time.Duration(-b.tokens / b.rate * float64(time.Second)), true
+}
+
+// cancel returns a reserved token when the caller gave up waiting
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// limiter combines a token bucket, an in-flight semaphore and a server-imposed pause
+type limiter struct {
+	name   string
+	bucket *tokenBucket
+	slots  chan struct{}
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+}
+
+// newLimiter creates a limiter for a RateLimit; a nil limit only supports server-imposed pauses
+func newLimiter(name string, limit *RateLimit) *limiter {
+	l := &limiter{name: name}
+	if limit != nil {
+		l.bucket = newTokenBucket(limit.RequestsPerSecond, limit.Burst)
+		if limit.MaxInFlight > 0 {
+			l.slots = make(chan struct{}, limit.MaxInFlight)
+		}
+	}
+	return l
+}
+
+// acquire waits for the limiter, or fails immediately when failFast is set, and returns a release function
+func (l *limiter) acquire(ctx context.Context, failFast bool) (func(), error) {
+	// Honour a pause requested by the server
+	l.mu.Lock()
+	pause := time.Until(l.blockedUntil)
+	l.mu.Unlock()
+	if pause > 0 {
+		if failFast {
+			return nil, fmt.Errorf("%w: %s paused for %s", ErrRateLimited, l.name, pause.Round(time.Millisecond))
+		}
+		if err := sleepContext(ctx, pause); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.bucket != nil {
+		wait, ok := l.bucket.reserve(failFast)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRateLimited, l.name)
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			l.bucket.cancel()
+			return nil, err
+		}
+	}
+
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	if failFast {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return nil, fmt.Errorf("%w: %s has too many requests in flight", ErrRateLimited, l.name)
+		}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { <-l.slots }, nil
+}
+
+// pauseUntil blocks new requests through this limiter until t
+func (l *limiter) pauseUntil(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t.After(l.blockedUntil) {
+		l.blockedUntil = t
+		LogWarning("rate-limit", fmt.Sprintf("%s paused until %s", l.name, t.Format(time.RFC3339)))
+	}
+}
+
+// sleepContext waits for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// routeLimiter applies a limiter to URLs matching a pattern
+type routeLimiter struct {
+	pattern string
+	limiter *limiter
+}
+
+// rateLimiters holds the limiters of the current configuration
+type rateLimiters struct {
+	cfg    *RateLimitConfig
+	global *limiter
+	routes []routeLimiter
+
+	mu    sync.Mutex
+	hosts map[string]*limiter
+}
+
+var limiters *rateLimiters
+
+// newRateLimiters creates the limiters for a configuration, or nil when disabled
+func newRateLimiters(cfg *RateLimitConfig) *rateLimiters {
+	if cfg == nil {
+		return nil
+	}
+
+	r := &rateLimiters{
+		cfg:   cfg,
+		hosts: make(map[string]*limiter),
+	}
+	if cfg.Global != nil {
+		r.global = newLimiter("global", cfg.Global)
+	}
+	for _, route := range cfg.Routes {
+		limit := route.Limit
+		r.routes = append(r.routes, routeLimiter{
+			pattern: route.Pattern,
+			limiter: newLimiter("route "+route.Pattern, &limit),
+		})
+	}
+	return r
+}
+
+// host returns the limiter for a host, creating it on first use. It returns nil when the
+// host has no limit and responses are not adapted to.
+func (r *rateLimiters) host(host string) *limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.hosts[host]; ok {
+		return l
+	}
+
+	limit, ok := r.cfg.PerHost[host]
+	if !ok {
+		limit = r.cfg.DefaultPerHost
+	}
+	if limit == nil && !r.cfg.AdaptToHeaders {
+		return nil
+	}
+
+	l := newLimiter("host "+host, limit)
+	r.hosts[host] = l
+	return l
+}
+
+// route returns the limiter of the first route pattern matching the URL
+func (r *rateLimiters) route(u *url.URL) *limiter {
+	for _, route := range r.routes {
+		// Patterns starting with "/" match the path only, others match host and path
+		target := u.Host + u.Path
+		if strings.HasPrefix(route.pattern, "/") {
+			target = u.Path
+		}
+		if matched, _ := path.Match(route.pattern, target); matched {
+			return route.limiter
+		}
+	}
+	return nil
+}
+
+// acquire waits for the global, host and route limiters in that order and returns a release function
+func (r *rateLimiters) acquire(ctx context.Context, urlStr string) (func(), error) {
+	if r == nil {
+		return func() {}, nil
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+
+	for _, l := range []*limiter{r.global, r.host(u.Host), r.route(u)} {
+		if l == nil {
+			continue
+		}
+		done, err := l.acquire(ctx, r.cfg.FailFast)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, done)
+	}
+
+	return release, nil
+}
+
+// observe pauses the host limiter when the response reports an exhausted quota
+func (r *rateLimiters) observe(urlStr string, resp *Response) {
+	if r == nil || !r.cfg.AdaptToHeaders || resp == nil {
+		return
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return
+	}
+	l := r.host(u.Host)
+	if l == nil {
+		return
+	}
+
+	if until, ok := rateLimitPause(resp.Header, resp.StatusCode); ok {
+		l.pauseUntil(until)
+	}
+}
+
+// rateLimitPause derives when requests may resume from X-RateLimit-Remaining/X-RateLimit-Reset,
+// or from Retry-After on 429 and 503 responses
+func rateLimitPause(header http.Header, statusCode int) (time.Time, bool) {
+	if remaining := header.Get("X-RateLimit-Remaining"); remaining != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(remaining)); err == nil && n <= 0 {
+			if reset, ok := parseResetHeader(header.Get("X-RateLimit-Reset")); ok {
+				return reset, true
+			}
+		}
+	}
+
+	if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+		if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil {
+				return time.Now().Add(time.Duration(seconds) * time.Second), true
+			}
+			if t, err := http.ParseTime(retryAfter); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseResetHeader parses a reset value given either as seconds from now or as a Unix timestamp
+func parseResetHeader(value string) (time.Time, bool) {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, false
+	}
+
+	// Values this large can only be Unix timestamps
+	if seconds > 1e9 {
+		return time.Unix(int64(seconds), 0), true
+	}
+	return time.Now().Add(time.Duration(seconds * float64(time.Second))), true
+}
@@ -0,0 +1,129 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketDebt(t *testing.T) {
+	bucket := newTokenBucket(10, 1)
+
+	if wait, ok := bucket.reserve(false); !ok || wait != 0 {
+		t.Fatalf("first reserve = %s, %v; want no wait", wait, ok)
+	}
+
+	// Each further reservation goes deeper into debt and waits longer
+	first, _ := bucket.reserve(false)
+	second, _ := bucket.reserve(false)
+	if first < 90*time.Millisecond || first > 100*time.Millisecond {
+		t.Fatalf("first wait = %s, want about 100ms", first)
+	}
+	if second < 190*time.Millisecond || second > 200*time.Millisecond {
+		t.Fatalf("second wait = %s, want about 200ms", second)
+	}
+
+	// Fail-fast callers never go into debt
+	if _, ok := bucket.reserve(true); ok {
+		t.Fatal("expected a fail-fast reserve to be refused while in debt")
+	}
+
+	// Cancelling repays the debt of a reservation that was never used
+	bucket.cancel()
+	if wait, _ := bucket.reserve(false); wait > 200*time.Millisecond {
+		t.Fatalf("wait after cancel = %s, want at most 200ms", wait)
+	}
+}
+
+func TestLimiterCancelledWaitReturnsToken(t *testing.T) {
+	l := newLimiter("test", &RateLimit{RequestsPerSecond: 1, Burst: 1})
+	if _, err := l.acquire(context.Background(), false); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to be cut off, got %v", err)
+	}
+
+	l.bucket.mu.Lock()
+	tokens := l.bucket.tokens
+	l.bucket.mu.Unlock()
+	if tokens < -0.1 {
+		t.Fatalf("cancelled wait left the bucket in debt: %f tokens", tokens)
+	}
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	l := newLimiter("test", &RateLimit{MaxInFlight: 1})
+	release, err := l.acquire(context.Background(), false)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	if _, err := l.acquire(context.Background(), true); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited while the slot is taken, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait for a slot to be cut off, got %v", err)
+	}
+
+	release()
+	if _, err := l.acquire(context.Background(), true); err != nil {
+		t.Fatalf("expected the released slot to be free, got %v", err)
+	}
+}
+
+func TestRateLimitFailFast(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig().WithRateLimit(&RateLimitConfig{
+		Global:   &RateLimit{RequestsPerSecond: 1, Burst: 1},
+		FailFast: true,
+	})
+	cfg.RetryConfig.MaxRetries = 2
+	initTestConfig(t, cfg)
+
+	if _, err := MakeGETRequest("Limited", server.URL, nil, nil); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if _, err := MakeGETRequest("Limited", server.URL, nil, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected 1 request to reach the server, got %d", n)
+	}
+}
+
+func TestRateLimitPausesOnRetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithRateLimit(&RateLimitConfig{AdaptToHeaders: true, FailFast: true}))
+
+	MakeGETRequest("Paused", server.URL, nil, nil)
+	if _, err := MakeGETRequest("Paused", server.URL, nil, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected the host to be paused, got %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("expected 1 request to reach the server, got %d", n)
+	}
+}
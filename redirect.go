@@ -0,0 +1,108 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxRedirects matches the limit of Go's default client
+const defaultMaxRedirects = 10
+
+// Errors returned, wrapped in a *url.Error, when a redirect is refused by the RedirectConfig
+var (
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrRedirectDowngrade = errors.New("redirect from https to http refused")
+	ErrCrossHostRedirect = errors.New("redirect to another host refused")
+)
+
+// Redirect is one hop followed while sending a request
+type Redirect struct {
+	StatusCode int    // Status of the redirect response
+	URL        string // Location that was followed, with any password redacted
+}
+
+// redirectPolicy returns the CheckRedirect function enforcing cfg
+func redirectPolicy(cfg *RedirectConfig) func(req *http.Request, via []*http.Request) error {
+	maxRedirects := cfg.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		// Hand the redirect response back to the caller instead of following it
+		if cfg.Disable {
+			return http.ErrUseLastResponse
+		}
+
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, maxRedirects)
+		}
+
+		previous := via[len(via)-1]
+		if previous.URL.Scheme == "https" && req.URL.Scheme == "http" && !cfg.AllowDowngrade {
+			return ErrRedirectDowngrade
+		}
+
+		original := via[0]
+		if !strings.EqualFold(req.URL.Hostname(), original.URL.Hostname()) {
+			if cfg.SameHostOnly {
+				return fmt.Errorf("%w: %s", ErrCrossHostRedirect, req.URL.Host)
+			}
+			copySensitiveHeaders(req, original, cfg.PreserveSensitiveHeaders)
+		}
+		return nil
+	}
+}
+
+// copySensitiveHeaders restores the credentials of the original request on a cross-host redirect
+// when preserve is set, and otherwise removes them. Go only strips Authorization, Cookie and
+// WWW-Authenticate itself, and not for subdomains.
+func copySensitiveHeaders(req, original *http.Request, preserve bool) {
+	for key, values := range original.Header {
+		if !isSensitiveHeader(key) {
+			continue
+		}
+		if preserve {
+			req.Header[key] = values
+		} else {
+			req.Header.Del(key)
+		}
+	}
+}
+
+// isRefusedRedirect reports whether err is a redirect refused by the RedirectConfig. The refusal
+// is made by the client's policy, so it says nothing about the upstream's health and a retry would
+// be refused again.
+func isRefusedRedirect(err error) bool {
+	return errors.Is(err, ErrTooManyRedirects) || errors.Is(err, ErrRedirectDowngrade) || errors.Is(err, ErrCrossHostRedirect)
+}
+
+// redirectChain returns the hops followed before resp, oldest first
+func redirectChain(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]Redirect{{StatusCode: req.Response.StatusCode, URL: req.URL.Redacted()}}, chain...)
+	}
+	return chain
+}
+
+// formatRedirects formats a redirect chain for logging
+func formatRedirects(chain []Redirect) string {
+	hops := make([]string, len(chain))
+	for i, hop := range chain {
+		hops[i] = fmt.Sprintf("%d %s", hop.StatusCode, hop.URL)
+	}
+	return strings.Join(hops, " -> ")
+}
+
+// withRedirectPolicy returns a copy of client that follows redirects according to cfg
+func withRedirectPolicy(client *http.Client, cfg *RedirectConfig) *http.Client {
+	if cfg == nil {
+		return client
+	}
+	withPolicy := *client
+	withPolicy.CheckRedirect = redirectPolicy(cfg)
+	return &withPolicy
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// localhostURL returns the URL of server under the host name localhost, which a redirect
+// policy sees as another host than 127.0.0.1
+func localhostURL(server *httptest.Server) string {
+	return strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+}
+
+func TestRedirectChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig().WithRedirects(&RedirectConfig{}))
+
+	resp, err := MakeRequestForResponse(context.Background(), "GET", "Get Page", server.URL+"/a", nil, nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	want := []Redirect{{http.StatusFound, server.URL + "/b"}, {http.StatusMovedPermanently, server.URL + "/c"}}
+	if len(resp.Redirects) != len(want) {
+		t.Fatalf("Redirects = %v, want %v", resp.Redirects, want)
+	}
+	for i := range want {
+		if resp.Redirects[i] != want[i] {
+			t.Fatalf("Redirects = %v, want %v", resp.Redirects, want)
+		}
+	}
+}
+
+func TestTooManyRedirectsIsNotRetriedOrCountedAgainstTheCircuit(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			return
+		}
+		hits.Add(1)
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig().
+		WithRedirects(&RedirectConfig{MaxRedirects: 3}).
+		WithCircuitBreaker(&CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Minute})
+	cfg.RetryConfig.MaxRetries = 2
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	_, err := MakeGETRequest("Get Loop", server.URL+"/loop", nil, nil)
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("error = %v, want ErrTooManyRedirects", err)
+	}
+	if got := hits.Load(); got != 4 {
+		t.Fatalf("server got %d requests, want the original and 3 redirects without retries", got)
+	}
+
+	// The refusal was made by the client, so the circuit of the host stays closed
+	if _, err := MakeGETRequest("Get OK", server.URL+"/ok", nil, nil); err != nil {
+		t.Fatalf("request after a refused redirect failed: %v", err)
+	}
+}
+
+func TestRedirectDowngrade(t *testing.T) {
+	original, _ := http.NewRequest("GET", "https://api.example.com/a", nil)
+	downgrade, _ := http.NewRequest("GET", "http://api.example.com/b", nil)
+	via := []*http.Request{original}
+
+	if err := redirectPolicy(&RedirectConfig{})(downgrade, via); !errors.Is(err, ErrRedirectDowngrade) {
+		t.Fatalf("error = %v, want ErrRedirectDowngrade", err)
+	}
+	if err := redirectPolicy(&RedirectConfig{AllowDowngrade: true})(downgrade, via); err != nil {
+		t.Fatalf("error = %v with AllowDowngrade, want the redirect followed", err)
+	}
+}
+
+func TestRedirectSameHostOnly(t *testing.T) {
+	var hits atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, localhostURL(server)+"/there", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig().WithRedirects(&RedirectConfig{SameHostOnly: true}))
+
+	_, err := MakeGETRequest("Get Moved", server.URL+"/moved", nil, nil)
+	if !errors.Is(err, ErrCrossHostRedirect) {
+		t.Fatalf("error = %v, want ErrCrossHostRedirect", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("server got %d requests, want the redirect to another host not followed", got)
+	}
+}
+
+func TestRedirectToAnotherHostStripsCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		preserve bool
+	}{
+		{"stripped", false},
+		{"preserved", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received http.Header
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/moved" {
+					http.Redirect(w, r, localhostURL(server)+"/there", http.StatusFound)
+					return
+				}
+				received = r.Header.Clone()
+			}))
+			defer server.Close()
+			initTestConfig(t, newTestConfig().WithRedirects(&RedirectConfig{PreserveSensitiveHeaders: tt.preserve}))
+
+			headers := map[string]string{"Authorization": "Bearer secret", "X-Api-Key": "key", "Accept": "application/json"}
+			if _, err := MakeGETRequest("Get Moved", server.URL+"/moved", nil, headers); err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+
+			if received.Get("Accept") != "application/json" {
+				t.Fatalf("Accept = %q after the redirect, want it kept", received.Get("Accept"))
+			}
+			for _, key := range []string{"Authorization", "X-Api-Key"} {
+				if got := received.Get(key) != ""; got != tt.preserve {
+					t.Fatalf("%s sent to the other host: %v, want %v", key, got, tt.preserve)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// defaultRequestIDHeader is used when RequestIDConfig.Header is not set
+const defaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose calls use id as their request ID instead of generating one
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by WithRequestID, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDError is returned for failed calls when request IDs are enabled. It wraps the
+// original error, so errors.Is and errors.As keep working.
+type RequestIDError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestIDError) Error() string {
+	return fmt.Sprintf("%v (request ID %s)", e.Err, e.RequestID)
+}
+
+func (e *RequestIDError) Unwrap() error {
+	return e.Err
+}
+
+// RequestIDFromError returns the request ID of a failed call, if it has one
+func RequestIDFromError(err error) (string, bool) {
+	var idErr *RequestIDError
+	if errors.As(err, &idErr) {
+		return idErr.RequestID, true
+	}
+	return "", false
+}
+
+// newRequestID returns the ID of a call: the one set explicitly in its headers, the one in its
+// context, or a generated one. It returns an empty string when request IDs are disabled.
+func newRequestID(ctx context.Context, headers map[string]string) string {
+	cfg := config.RequestIDConfig
+	if cfg == nil {
+		return ""
+	}
+
+	if id := headerValue(headers, requestIDHeader()); id != "" {
+		return id
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		return id
+	}
+	if cfg.Generate != nil {
+		return cfg.Generate()
+	}
+	return randomRequestID()
+}
+
+// randomRequestID returns 16 random hexadecimal characters
+func randomRequestID() string {
+	var buf [8]byte
+	if _, err := io.ReadFull(rand.Reader, buf[:]); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", buf[:])
+}
+
+// requestIDHeader returns the header carrying request IDs
+func requestIDHeader() string {
+	if config.RequestIDConfig != nil && config.RequestIDConfig.Header != "" {
+		return config.RequestIDConfig.Header
+	}
+	return defaultRequestIDHeader
+}
+
+// attemptRequestID returns the ID sent with an attempt: the call's ID for the first attempt,
+// suffixed with the attempt number for retries
+func attemptRequestID(id string, attempt int) string {
+	if id == "" || attempt <= 1 {
+		return id
+	}
+	return id + "-" + strconv.Itoa(attempt)
+}
+
+// withRequestIDError attaches the request ID of a call to its error
+func withRequestIDError(id string, err error) error {
+	if id == "" || err == nil {
+		return err
+	}
+	return &RequestIDError{RequestID: id, Err: err}
+}
@@ -0,0 +1,110 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestIDPrecedence(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Correlation-ID")
+	}))
+	defer server.Close()
+
+	initTestConfig(t, newTestConfig().WithRequestID(&RequestIDConfig{
+		Header:   "X-Correlation-ID",
+		Generate: func() string { return "generated" },
+	}))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		headers map[string]string
+		want    string
+	}{
+		{"header wins", WithRequestID(context.Background(), "from-context"), map[string]string{"x-correlation-id": "from-header"}, "from-header"},
+		{"context", WithRequestID(context.Background(), "from-context"), nil, "from-context"},
+		{"generated", context.Background(), nil, "generated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := MakeRequestForResponse(tt.ctx, "GET", "Get Users", server.URL, nil, tt.headers)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if received != tt.want || resp.RequestID != tt.want {
+				t.Fatalf("sent %q and returned %q, want %q", received, resp.RequestID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestIDOnRetriesAndErrors(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("X-Request-ID"))
+		mu.Unlock()
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig().WithRequestID(&RequestIDConfig{})
+	cfg.RetryConfig.MaxRetries = 2
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	ctx := WithRequestID(context.Background(), "call-1")
+	resp, err := MakeRequestForResponse(ctx, "GET", "Get Users", server.URL, nil, nil)
+	if err == nil {
+		t.Fatal("expected the final 404 to fail the call")
+	}
+
+	// Retries carry the call's ID with the attempt number, so upstream logs can tell them apart
+	want := []string{"call-1", "call-1-2", "call-1-3"}
+	if len(received) != len(want) {
+		t.Fatalf("server got IDs %v, want %v", received, want)
+	}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Fatalf("server got IDs %v, want %v", received, want)
+		}
+	}
+
+	if id, ok := RequestIDFromError(err); !ok || id != "call-1" {
+		t.Fatalf("RequestIDFromError = %q, %v; want call-1", id, ok)
+	}
+	if resp == nil || resp.RequestID != "call-1" {
+		t.Fatalf("response request ID = %v, want call-1", resp)
+	}
+}
+
+func TestRequestIDDisabled(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Request-ID")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	initTestConfig(t, newTestConfig())
+
+	_, err := MakeRequestForResponse(WithRequestID(context.Background(), "ignored"), "GET", "Get Users", server.URL, nil, nil)
+	if received != "" {
+		t.Fatalf("sent X-Request-ID %q while request IDs are disabled", received)
+	}
+	if _, ok := RequestIDFromError(err); ok {
+		t.Fatal("expected no request ID on the error while request IDs are disabled")
+	}
+}
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultRetryBudgetWindow is used when RetryBudgetConfig.Window is not set
+const defaultRetryBudgetWindow = 10 * time.Second
+
+// budgetBucket counts requests and retries during one second
+type budgetBucket struct {
+	second   int64
+	requests int
+	retries  int
+}
+
+// retryBudget limits retries to a fraction of the requests seen over a sliding window
+type retryBudget struct {
+	key string
+	cfg *RetryBudgetConfig
+
+	mu        sync.Mutex
+	buckets   []budgetBucket
+	exhausted bool
+}
+
+// retryBudgets holds the budgets of the current configuration, one per host or a single shared one
+type retryBudgets struct {
+	cfg *RetryBudgetConfig
+
+	mu      sync.Mutex
+	budgets map[string]*retryBudget
+}
+
+var budgets *retryBudgets
+
+// newRetryBudgets creates the retry budgets for a configuration, or nil when disabled
+func newRetryBudgets(cfg *RetryBudgetConfig) *retryBudgets {
+	if cfg == nil {
+		return nil
+	}
+	return &retryBudgets{
+		cfg:     cfg,
+		budgets: make(map[string]*retryBudget),
+	}
+}
+
+// get returns the budget for a request, creating it on first use
+func (r *retryBudgets) get(urlStr string) *retryBudget {
+	if r == nil {
+		return nil
+	}
+
+	key := "client"
+	if r.cfg.PerHost {
+		if u, err := url.Parse(urlStr); err == nil {
+			key = u.Host
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	budget, ok := r.budgets[key]
+	if !ok {
+		window := r.cfg.Window
+		if window <= 0 {
+			window = defaultRetryBudgetWindow
+		}
+		seconds := int(window / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+
+		budget = &retryBudget{
+			key:     key,
+			cfg:     r.cfg,
+			buckets: make([]budgetBucket, seconds),
+		}
+		r.budgets[key] = budget
+	}
+	return budget
+}
+
+// bucket returns the bucket of the current second, resetting it if it holds an old second.
+// Callers hold b.mu.
+func (b *retryBudget) bucket(now time.Time) *budgetBucket {
+	second := now.Unix()
+	bucket := &b.buckets[second%int64(len(b.buckets))]
+	if bucket.second != second {
+		*bucket = budgetBucket{second: second}
+	}
+	return bucket
+}
+
+// totals sums requests and retries over the window. Callers hold b.mu.
+func (b *retryBudget) totals(now time.Time) (requests, retries int) {
+	oldest := now.Unix() - int64(len(b.buckets)) + 1
+	for _, bucket := range b.buckets {
+		if bucket.second >= oldest {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	return requests, retries
+}
+
+// recordRequest counts a new logical request
+func (b *retryBudget) recordRequest() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket(time.Now()).requests++
+}
+
+// tryRetry withdraws a retry from the budget, returning false and logging once when it is exhausted
+func (b *retryBudget) tryRetry() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	requests, retries := b.totals(now)
+	allowed := b.cfg.Ratio*float64(requests) + b.cfg.MinRetriesPerSecond*float64(len(b.buckets))
+
+	if float64(retries) >= allowed {
+		if !b.exhausted {
+			b.exhausted = true
+			LogWarning("retry-budget", fmt.Sprintf("%s: budget exhausted (%d retries for %d requests in %ds), not retrying",
+				b.key, retries, requests, len(b.buckets)))
+		}
+		return false
+	}
+
+	if b.exhausted {
+		b.exhausted = false
+		LogInfo("retry-budget", fmt.Sprintf("%s: budget available again", b.key))
+	}
+	b.bucket(now).retries++
+	return true
+}
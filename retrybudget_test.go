@@ -0,0 +1,72 @@
+package network
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetRatio(t *testing.T) {
+	initTestConfig(t, newTestConfig())
+	budget := newRetryBudgets(&RetryBudgetConfig{Ratio: 0.2}).get("http://example.com")
+
+	for i := 0; i < 10; i++ {
+		budget.recordRequest()
+	}
+	for i := 0; i < 2; i++ {
+		if !budget.tryRetry() {
+			t.Fatalf("retry %d was refused within the budget", i+1)
+		}
+	}
+	if budget.tryRetry() {
+		t.Fatal("expected the third retry to exceed 20% of 10 requests")
+	}
+}
+
+func TestRetryBudgetWindowSlides(t *testing.T) {
+	initTestConfig(t, newTestConfig())
+	budget := newRetryBudgets(&RetryBudgetConfig{Ratio: 1, Window: 3 * time.Second}).get("http://example.com")
+
+	budget.recordRequest()
+	budget.tryRetry()
+
+	now := time.Now()
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	if requests, retries := budget.totals(now.Add(2 * time.Second)); requests != 1 || retries != 1 {
+		t.Fatalf("within the window: %d requests, %d retries; want 1 and 1", requests, retries)
+	}
+	if requests, retries := budget.totals(now.Add(3 * time.Second)); requests != 0 || retries != 0 {
+		t.Fatalf("after the window: %d requests, %d retries; want 0 and 0", requests, retries)
+	}
+
+	// A bucket reused for a later second starts empty
+	if bucket := budget.bucket(now.Add(3 * time.Second)); bucket.requests != 0 || bucket.retries != 0 {
+		t.Fatalf("reused bucket kept %d requests and %d retries", bucket.requests, bucket.retries)
+	}
+}
+
+func TestRetryBudgetLimitsRetriesAcrossCalls(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.MaxRetries = 5
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	cfg.RetryConfig.Budget = &RetryBudgetConfig{MinRetriesPerSecond: 0.1, Window: 10 * time.Second}
+	initTestConfig(t, cfg)
+
+	// One retry is allowed over the whole window, shared by both calls
+	MakeGETRequest("Budget", server.URL, nil, nil)
+	MakeGETRequest("Budget", server.URL, nil, nil)
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("expected 3 requests (2 calls and 1 retry), got %d", n)
+	}
+}
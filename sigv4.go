@@ -0,0 +1,264 @@
+package network
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm       = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat      = "20060102T150405Z"
+	sigV4DateFormat      = "20060102"
+	sigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sigV4IgnoredHeaders are never signed because proxies and transports may change them
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+	"connection":      true,
+}
+
+// SigV4Config holds the AWS credentials and scope used to sign requests with Signature Version 4
+type SigV4Config struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Sent as X-Amz-Security-Token when set, for temporary credentials
+	Region          string
+	Service         string // e.g. "execute-api" or "s3"
+	UnsignedPayload bool   // Sign UNSIGNED-PAYLOAD instead of hashing the body; always used for streamed bodies
+}
+
+// SigV4Middleware returns a middleware that signs every attempt with AWS Signature Version 4
+func SigV4Middleware(cfg *SigV4Config) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if err := SignSigV4(req, cfg, time.Now()); err != nil {
+				return nil, fmt.Errorf("sigv4: %w", err)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// SignSigV4 signs req in place as of signingTime, setting the X-Amz-Date, X-Amz-Security-Token,
+// X-Amz-Content-Sha256 (S3 and unsigned payloads only) and Authorization headers. The body is
+// hashed through req.GetBody; bodies without GetBody are streams and sign UNSIGNED-PAYLOAD.
+func SignSigV4(req *http.Request, cfg *SigV4Config, signingTime time.Time) error {
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return errors.New("access key ID and secret access key are required")
+	}
+	if cfg.Region == "" || cfg.Service == "" {
+		return errors.New("region and service are required")
+	}
+
+	signingTime = signingTime.UTC()
+	amzDate := signingTime.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{signingTime.Format(sigV4DateFormat), cfg.Region, cfg.Service, "aws4_request"}, "/")
+
+	payloadHash, err := sigV4PayloadHash(req, cfg)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+	}
+	if cfg.Service == "s3" || payloadHash == sigV4UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := sigV4CanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4CanonicalURI(req.URL, cfg.Service != "s3"),
+		sigV4CanonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretAccessKey), signingTime.Format(sigV4DateFormat))
+	for _, part := range []string{cfg.Region, cfg.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, cfg.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// sigV4PayloadHash returns the hex SHA-256 of the body, or UNSIGNED-PAYLOAD
+func sigV4PayloadHash(req *http.Request, cfg *SigV4Config) (string, error) {
+	if cfg.UnsignedPayload {
+		return sigV4UnsignedPayload, nil
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return hexSHA256(nil), nil
+	}
+	if req.GetBody == nil {
+		return sigV4UnsignedPayload, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sigV4CanonicalURI returns the URI-encoded path. Services other than S3 normalize the path,
+// removing empty, "." and ".." segments, and encode it a second time. Segments are decoded
+// before encoding so characters Go leaves unescaped, such as '+' and ':', are encoded too.
+func sigV4CanonicalURI(u *url.URL, normalize bool) string {
+	escapedPath := u.EscapedPath()
+	var segments []string
+	for _, segment := range strings.Split(escapedPath, "/") {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			decoded = segment
+		}
+
+		if normalize {
+			switch decoded {
+			case "", ".":
+				continue
+			case "..":
+				if len(segments) > 0 {
+					segments = segments[:len(segments)-1]
+				}
+				continue
+			}
+		}
+
+		encoded := sigV4Escape(decoded)
+		if normalize {
+			encoded = sigV4Escape(encoded)
+		}
+		segments = append(segments, encoded)
+	}
+
+	if !normalize {
+		if escapedPath == "" {
+			return "/"
+		}
+		return strings.Join(segments, "/")
+	}
+
+	canonical := "/" + strings.Join(segments, "/")
+	if len(segments) > 0 && strings.HasSuffix(escapedPath, "/") {
+		canonical += "/"
+	}
+	return canonical
+}
+
+// sigV4CanonicalQuery returns the URI-encoded query parameters sorted by name, then by value
+func sigV4CanonicalQuery(u *url.URL) string {
+	type pair struct{ key, value string }
+	var pairs []pair
+	for key, values := range u.Query() {
+		for _, value := range values {
+			pairs = append(pairs, pair{sigV4Escape(key), sigV4Escape(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].key != pairs[j].key {
+			return pairs[i].key < pairs[j].key
+		}
+		return pairs[i].value < pairs[j].value
+	})
+
+	encoded := make([]string, len(pairs))
+	for i, p := range pairs {
+		encoded[i] = p.key + "=" + p.value
+	}
+	return strings.Join(encoded, "&")
+}
+
+// sigV4CanonicalHeaders returns the canonical headers block and the signed headers list
+func sigV4CanonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	values := map[string][]string{"host": {host}}
+	for key, headerValues := range req.Header {
+		name := strings.ToLower(key)
+		if sigV4IgnoredHeaders[name] || name == "host" {
+			continue
+		}
+		values[name] = append(values[name], headerValues...)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		trimmed := make([]string, len(values[name]))
+		for i, value := range values[name] {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		canonical.WriteString(name + ":" + strings.Join(trimmed, ",") + "\n")
+	}
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// sigV4Escape URI-encodes every byte except the unreserved characters A-Z, a-z, 0-9, '-', '.', '_' and '~'
+func sigV4Escape(s string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// hexSHA256 returns the hex-encoded SHA-256 of data
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
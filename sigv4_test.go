@@ -0,0 +1,103 @@
+package network
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectors from the AWS Signature Version 4 test suite
+func TestSignSigV4TestSuite(t *testing.T) {
+	cfg := &SigV4Config{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+	}
+	signingTime := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		contentType   string
+		signedHeaders string
+		signature     string
+	}{
+		{"get-vanilla", "GET", "/", "", "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-relative", "GET", "/example/..", "", "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-relative-relative", "GET", "/example1/example2/../..", "", "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-slash", "GET", "//", "", "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-slash-dot-slash", "GET", "/./", "", "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-slashes", "GET", "//example//", "", "", "host;x-amz-date", "9a624bd73a37c9a373b5312afbebe7a714a789de108f0bdfe846570885f57e84"},
+		{"get-vanilla-query-order-key-case", "GET", "/?Param2=value2&Param1=value1", "", "", "host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-query-order-value", "GET", "/?Param1=value2&Param1=Value1", "", "", "host;x-amz-date", "eedbc4e291e521cf13422ffca22be7d2eb8146eecf653089df300a15b2382bd1"},
+		{"post-vanilla", "POST", "/", "", "", "host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-x-www-form-urlencoded", "POST", "/", "Param1=value1", "application/x-www-form-urlencoded", "content-type;host;x-amz-date", "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com"+tt.path, body)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			if err := SignSigV4(req, cfg, signingTime); err != nil {
+				t.Fatalf("SignSigV4: %v", err)
+			}
+
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != expected {
+				t.Fatalf("Authorization =\n%s\nwant\n%s", got, expected)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Fatalf("X-Amz-Date = %q", got)
+			}
+		})
+	}
+}
+
+func TestSigV4CanonicalURIEncodesReservedCharacters(t *testing.T) {
+	u, err := url.Parse("https://example.amazonaws.com/a+b/c:d(e)*,;=@$!/f%2Fg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := sigV4CanonicalURI(u, false), "/a%2Bb/c%3Ad%28e%29%2A%2C%3B%3D%40%24%21/f%2Fg"; got != want {
+		t.Fatalf("S3 canonical URI = %q, want %q", got, want)
+	}
+	if got, want := sigV4CanonicalURI(u, true), "/a%252Bb/c%253Ad%2528e%2529%252A%252C%253B%253D%2540%2524%2521/f%252Fg"; got != want {
+		t.Fatalf("canonical URI = %q, want %q", got, want)
+	}
+
+	// S3 object keys are signed as they are, without normalization
+	s3, _ := url.Parse("https://bucket.s3.amazonaws.com//key/../x/")
+	if got, want := sigV4CanonicalURI(s3, false), "//key/../x/"; got != want {
+		t.Fatalf("S3 canonical URI = %q, want %q", got, want)
+	}
+}
+
+func TestSigV4CanonicalQuerySortsByKeyThenValue(t *testing.T) {
+	u, err := url.Parse("https://example.amazonaws.com/?a-b=1&a=2&a=1&b=%20x+y")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sorting whole "key=value" strings would put "a-b" before "a" because '-' sorts before '='
+	if got, want := sigV4CanonicalQuery(u), "a=1&a=2&a-b=1&b=%20x%20y"; got != want {
+		t.Fatalf("canonical query = %q, want %q", got, want)
+	}
+}
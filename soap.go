@@ -0,0 +1,422 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SOAPVersion selects the SOAP envelope version
+type SOAPVersion int
+
+const (
+	SOAP11 SOAPVersion = iota
+	SOAP12
+)
+
+// SOAP envelope and WS-Security namespaces
+const (
+	soap11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+
+	wsseNamespace      = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNamespace       = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	wssPasswordText    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	wssPasswordDigest  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	wssBase64Encoding  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+	redactedPassword   = "***"
+	soapFaultLocalName = "Fault"
+)
+
+// SOAPRequest describes a SOAP call. Body and each entry of Headers are marshalled
+// with encoding/xml, so they should carry an XMLName with the operation namespace.
+type SOAPRequest struct {
+	Version  SOAPVersion
+	Action   string
+	Headers  []interface{}
+	Security *WSSecurity
+	Body     interface{}
+}
+
+// WSSecurity holds WS-Security UsernameToken credentials added to the SOAP header
+type WSSecurity struct {
+	Username       string
+	Password       string
+	PasswordDigest bool // Send a PasswordDigest with nonce and timestamp instead of PasswordText
+}
+
+// SOAPFault is returned when the service answers with a SOAP fault.
+// Fields are populated from either a SOAP 1.1 or a SOAP 1.2 fault.
+type SOAPFault struct {
+	Code    string
+	Subcode string
+	Reason  string
+	Actor   string
+	Detail  string // Raw inner XML of the detail element
+}
+
+// Error implements the error interface
+func (f *SOAPFault) Error() string {
+	code := f.Code
+	if f.Subcode != "" {
+		code += "/" + f.Subcode
+	}
+	return fmt.Sprintf("soap fault %s: %s", code, f.Reason)
+}
+
+// isServerFault reports whether the fault blames the service rather than the request
+func (f *SOAPFault) isServerFault() bool {
+	code := f.Code
+	if i := strings.LastIndex(code, ":"); i >= 0 {
+		code = code[i+1:]
+	}
+	return strings.HasPrefix(code, "Server") || code == "Receiver"
+}
+
+// checkSOAPFault turns a response carrying a SOAP fault into a *SOAPFault error
+func checkSOAPFault(resp *Response) error {
+	var fault *SOAPFault
+	if _, err := parseSOAPBody(resp.Body); errors.As(err, &fault) {
+		return fault
+	}
+	return nil
+}
+
+// NewSOAPRequest creates a SOAP 1.1 request for the given action and body
+func NewSOAPRequest(action string, body interface{}) *SOAPRequest {
+	return &SOAPRequest{
+		Version: SOAP11,
+		Action:  action,
+		Body:    body,
+	}
+}
+
+// WithVersion sets the SOAP envelope version
+func (r *SOAPRequest) WithVersion(version SOAPVersion) *SOAPRequest {
+	r.Version = version
+	return r
+}
+
+// WithHeader adds a SOAP header block
+func (r *SOAPRequest) WithHeader(header interface{}) *SOAPRequest {
+	r.Headers = append(r.Headers, header)
+	return r
+}
+
+// WithUsernameToken adds a WS-Security UsernameToken to the SOAP header
+func (r *SOAPRequest) WithUsernameToken(username, password string, digest bool) *SOAPRequest {
+	r.Security = &WSSecurity{
+		Username:       username,
+		Password:       password,
+		PasswordDigest: digest,
+	}
+	return r
+}
+
+// namespace returns the envelope namespace for the version
+func (v SOAPVersion) namespace() string {
+	if v == SOAP12 {
+		return soap12Namespace
+	}
+	return soap11Namespace
+}
+
+// soapEnvelope is the outgoing envelope; header and body are pre-marshalled
+type soapEnvelope struct {
+	XMLName   xml.Name    `xml:"soap:Envelope"`
+	Namespace string      `xml:"xmlns:soap,attr"`
+	Header    *soapHeader `xml:"soap:Header,omitempty"`
+	Body      soapBody    `xml:"soap:Body"`
+}
+
+type soapHeader struct {
+	Content string `xml:",innerxml"`
+}
+
+type soapBody struct {
+	Content string `xml:",innerxml"`
+}
+
+// wsseSecurity is the WS-Security header block
+type wsseSecurity struct {
+	XMLName        xml.Name          `xml:"wsse:Security"`
+	WSSENamespace  string            `xml:"xmlns:wsse,attr"`
+	WSUNamespace   string            `xml:"xmlns:wsu,attr"`
+	MustUnderstand string            `xml:"soap:mustUnderstand,attr"`
+	UsernameToken  wsseUsernameToken `xml:"wsse:UsernameToken"`
+}
+
+type wsseUsernameToken struct {
+	Username string       `xml:"wsse:Username"`
+	Password wssePassword `xml:"wsse:Password"`
+	Nonce    *wsseNonce   `xml:"wsse:Nonce,omitempty"`
+	Created  string       `xml:"wsu:Created,omitempty"`
+}
+
+type wssePassword struct {
+	Type  string `xml:"Type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type wsseNonce struct {
+	EncodingType string `xml:"EncodingType,attr"`
+	Value        string `xml:",chardata"`
+}
+
+// build creates the WS-Security header block, returning it with and without the password
+func (s *WSSecurity) build(mustUnderstand string) (*wsseSecurity, *wsseSecurity, error) {
+	token := wsseUsernameToken{
+		Username: s.Username,
+		Password: wssePassword{Type: wssPasswordText, Value: s.Password},
+	}
+
+	if s.PasswordDigest {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, nil, err
+		}
+		created := time.Now().UTC().Format(time.RFC3339)
+
+		// PasswordDigest = Base64(SHA-1(nonce + created + password))
+		hash := sha1.New()
+		hash.Write(nonce)
+		hash.Write([]byte(created))
+		hash.Write([]byte(s.Password))
+
+		token.Password = wssePassword{Type: wssPasswordDigest, Value: base64.StdEncoding.EncodeToString(hash.Sum(nil))}
+		token.Nonce = &wsseNonce{EncodingType: wssBase64Encoding, Value: base64.StdEncoding.EncodeToString(nonce)}
+		token.Created = created
+	}
+
+	security := &wsseSecurity{
+		WSSENamespace:  wsseNamespace,
+		WSUNamespace:   wsuNamespace,
+		MustUnderstand: mustUnderstand,
+		UsernameToken:  token,
+	}
+
+	// The nonce and timestamp are not secret, but change on every attempt
+	redacted := *security
+	redacted.UsernameToken.Password.Value = redactedPassword
+	if token.Nonce != nil {
+		redacted.UsernameToken.Nonce = &wsseNonce{EncodingType: wssBase64Encoding, Value: redactedPassword}
+		redacted.UsernameToken.Created = redactedPassword
+	}
+
+	return security, &redacted, nil
+}
+
+// buildEnvelope marshals the request into an envelope, returning it with and without credentials
+func (r *SOAPRequest) buildEnvelope() (string, string, error) {
+	body, err := xml.Marshal(r.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal SOAP body: %w", err)
+	}
+
+	var headerContent []byte
+	for _, header := range r.Headers {
+		encoded, err := xml.Marshal(header)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to marshal SOAP header: %w", err)
+		}
+		headerContent = append(headerContent, encoded...)
+	}
+
+	envelope := soapEnvelope{
+		Namespace: r.Version.namespace(),
+		Body:      soapBody{Content: string(body)},
+	}
+	if len(headerContent) > 0 {
+		envelope.Header = &soapHeader{Content: string(headerContent)}
+	}
+	redactedEnvelope := envelope
+
+	if r.Security != nil {
+		mustUnderstand := "1"
+		if r.Version == SOAP12 {
+			mustUnderstand = "true"
+		}
+		security, redacted, err := r.Security.build(mustUnderstand)
+		if err != nil {
+			return "", "", err
+		}
+
+		encoded, err := xml.Marshal(security)
+		if err != nil {
+			return "", "", err
+		}
+		encodedRedacted, err := xml.Marshal(redacted)
+		if err != nil {
+			return "", "", err
+		}
+
+		envelope.Header = &soapHeader{Content: string(encoded) + string(headerContent)}
+		redactedEnvelope.Header = &soapHeader{Content: string(encodedRedacted) + string(headerContent)}
+	}
+
+	payload, err := xml.Marshal(envelope)
+	if err != nil {
+		return "", "", err
+	}
+	logged, err := xml.Marshal(redactedEnvelope)
+	if err != nil {
+		return "", "", err
+	}
+
+	return xml.Header + string(payload), xml.Header + string(logged), nil
+}
+
+// soapHeaders returns the transport headers for the SOAP version and action
+func (r *SOAPRequest) soapHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers)+2)
+	for key, value := range headers {
+		result[key] = value
+	}
+
+	if r.Version == SOAP12 {
+		contentType := "application/soap+xml; charset=utf-8"
+		if r.Action != "" {
+			contentType += fmt.Sprintf("; action=%q", r.Action)
+		}
+		result["Content-Type"] = contentType
+	} else {
+		result["Content-Type"] = "text/xml; charset=utf-8"
+		result["SOAPAction"] = fmt.Sprintf("%q", r.Action)
+	}
+
+	return result
+}
+
+// soapResponseEnvelope matches the envelope of either SOAP version
+type soapResponseEnvelope struct {
+	Body struct {
+		Content []byte `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+// soapFaultXML matches both SOAP 1.1 and SOAP 1.2 fault elements
+type soapFaultXML struct {
+	// SOAP 1.1
+	FaultCode   string `xml:"faultcode"`
+	FaultString string `xml:"faultstring"`
+	FaultActor  string `xml:"faultactor"`
+	Detail11    struct {
+		Content string `xml:",innerxml"`
+	} `xml:"detail"`
+
+	// SOAP 1.2
+	Code struct {
+		Value   string `xml:"Value"`
+		Subcode struct {
+			Value string `xml:"Value"`
+		} `xml:"Subcode"`
+	} `xml:"Code"`
+	Reason struct {
+		Text string `xml:"Text"`
+	} `xml:"Reason"`
+	Role     string `xml:"Role"`
+	Detail12 struct {
+		Content string `xml:",innerxml"`
+	} `xml:"Detail"`
+}
+
+// toFault converts the parsed XML into a SOAPFault
+func (x *soapFaultXML) toFault() *SOAPFault {
+	if x.Code.Value != "" {
+		return &SOAPFault{
+			Code:    x.Code.Value,
+			Subcode: x.Code.Subcode.Value,
+			Reason:  strings.TrimSpace(x.Reason.Text),
+			Actor:   x.Role,
+			Detail:  strings.TrimSpace(x.Detail12.Content),
+		}
+	}
+	return &SOAPFault{
+		Code:   x.FaultCode,
+		Reason: strings.TrimSpace(x.FaultString),
+		Actor:  x.FaultActor,
+		Detail: strings.TrimSpace(x.Detail11.Content),
+	}
+}
+
+// parseSOAPBody extracts the body content, returning a SOAPFault if the body holds one
+func parseSOAPBody(response string) ([]byte, error) {
+	var envelope soapResponseEnvelope
+	if err := xml.Unmarshal([]byte(response), &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse SOAP envelope: %w", err)
+	}
+
+	// Look at the first element of the body to detect a fault
+	decoder := xml.NewDecoder(bytes.NewReader(envelope.Body.Content))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// Empty body
+			return envelope.Body.Content, nil
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == soapFaultLocalName {
+			var fault soapFaultXML
+			if err := decoder.DecodeElement(&fault, &start); err != nil {
+				return nil, fmt.Errorf("failed to parse SOAP fault: %w", err)
+			}
+			return nil, fault.toFault()
+		}
+		return envelope.Body.Content, nil
+	}
+}
+
+// MakeSOAPRequest sends a SOAP request and decodes the response body into response.
+// A SOAP fault is returned as a *SOAPFault error and is never retried; response may be nil
+// to skip decoding.
+func MakeSOAPRequest(description, urlStr string, request *SOAPRequest, response interface{}, headers map[string]string) (string, error) {
+	if request == nil {
+		return "", errors.New("soap request is nil")
+	}
+
+	// The first build validates the request and is logged with its credentials redacted
+	_, loggedPayload, err := request.buildEnvelope()
+	if err != nil {
+		return "", err
+	}
+
+	// Build the envelope again for every attempt, so each carries a fresh WS-Security nonce and timestamp
+	body := func() (io.Reader, error) {
+		payload, _, err := request.buildEnvelope()
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(payload), nil
+	}
+
+	// Faults are usually sent with a 500 status, so every attempt is checked for one
+	ctx := withResponseCheck(context.Background(), checkSOAPFault)
+	responseBody, requestErr := makeXMLRequest(ctx, methodPOST, description, urlStr, body, loggedPayload, request.soapHeaders(headers))
+	if requestErr != nil || responseBody == "" {
+		return responseBody, requestErr
+	}
+
+	content, err := parseSOAPBody(responseBody)
+	if err != nil {
+		return responseBody, err
+	}
+
+	if response != nil && len(bytes.TrimSpace(content)) > 0 {
+		if err := xml.Unmarshal(content, response); err != nil {
+			return responseBody, fmt.Errorf("failed to decode SOAP body: %w", err)
+		}
+	}
+
+	return responseBody, nil
+}
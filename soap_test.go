@@ -0,0 +1,91 @@
+package network
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+const soapClientFault = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <soap:Fault>
+      <faultcode>soap:Client</faultcode>
+      <faultstring>Invalid argument</faultstring>
+    </soap:Fault>
+  </soap:Body>
+</soap:Envelope>`
+
+type soapTestRequest struct {
+	XMLName xml.Name `xml:"http://tempuri.org/ Add"`
+	A       int      `xml:"a"`
+}
+
+func TestSOAPFaultIsNotRetried(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, soapClientFault)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.MaxRetries = 2
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	cfg.WithCircuitBreaker(&CircuitBreakerConfig{ConsecutiveFailures: 1, CoolDown: time.Minute})
+	initTestConfig(t, cfg)
+
+	for i := 0; i < 2; i++ {
+		_, err := MakeSOAPRequest("Add", server.URL, NewSOAPRequest("Add", soapTestRequest{A: 1}), nil, nil)
+		var fault *SOAPFault
+		if !errors.As(err, &fault) || fault.Code != "soap:Client" {
+			t.Fatalf("call %d: expected client fault, got %v", i+1, err)
+		}
+	}
+
+	// Neither retried nor counted by the circuit breaker
+	if hits != 2 {
+		t.Fatalf("expected 2 requests, got %d", hits)
+	}
+}
+
+func TestSOAPRetryUsesFreshNonce(t *testing.T) {
+	nonce := regexp.MustCompile(`<wsse:Nonce[^>]*>([^<]+)</wsse:Nonce>`)
+
+	var mu sync.Mutex
+	var nonces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if match := nonce.FindSubmatch(body); match != nil {
+			nonces = append(nonces, string(match[1]))
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig()
+	cfg.RetryConfig.MaxRetries = 2
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	request := NewSOAPRequest("Add", soapTestRequest{A: 1}).WithUsernameToken("user", "secret", true)
+	if _, err := MakeSOAPRequest("Add", server.URL, request, nil, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(nonces) != 3 {
+		t.Fatalf("expected 3 attempts with a nonce, got %d", len(nonces))
+	}
+	if nonces[0] == nonces[1] || nonces[1] == nonces[2] {
+		t.Fatalf("attempts reused a nonce: %v", nonces)
+	}
+}
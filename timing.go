@@ -0,0 +1,99 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings break down the time of one attempt. Phases that did not happen, such as DNS, connect
+// and TLS on a reused connection, are zero.
+type Timings struct {
+	DNSLookup        time.Duration
+	Connect          time.Duration // TCP connect
+	TLSHandshake     time.Duration
+	TimeToFirstByte  time.Duration // From sending the request to the first response byte
+	BodyTransfer     time.Duration // Reading the response body
+	ConnectionReused bool
+}
+
+// String formats the timings for logs
+func (t Timings) String() string {
+	return fmt.Sprintf("dns=%s connect=%s tls=%s ttfb=%s body=%s reused=%t",
+		t.DNSLookup, t.Connect, t.TLSHandshake, t.TimeToFirstByte, t.BodyTransfer, t.ConnectionReused)
+}
+
+// timingTrace collects Timings through net/http/httptrace. Callbacks can run on transport
+// goroutines, so every access is locked.
+type timingTrace struct {
+	mu      sync.Mutex
+	timings Timings
+
+	start, dnsStart, connectStart, tlsStart time.Time
+}
+
+// withTimingTrace returns a context that records the timings of the request it is attached to
+func withTimingTrace(ctx context.Context) (context.Context, *timingTrace) {
+	t := &timingTrace{}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.lock(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.lock(func() { t.timings.DNSLookup = since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.lock(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.lock(func() { t.timings.Connect = since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.lock(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock(func() { t.timings.TLSHandshake = since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock(func() { t.timings.ConnectionReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.lock(func() { t.timings.TimeToFirstByte = since(t.start) })
+		},
+	}), t
+}
+
+// lock runs f while holding the trace lock
+func (t *timingTrace) lock(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f()
+}
+
+// begin marks the moment the request is handed to the transport
+func (t *timingTrace) begin() {
+	t.lock(func() { t.start = time.Now() })
+}
+
+// bodyRead records how long reading the response body took
+func (t *timingTrace) bodyRead(duration time.Duration) {
+	t.lock(func() { t.timings.BodyTransfer = duration })
+}
+
+// result returns the timings collected so far
+func (t *timingTrace) result() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings
+}
+
+// since returns the time elapsed since start, or 0 when start was never set
+func since(start time.Time) time.Duration {
+	if start.IsZero() {
+		return 0
+	}
+	return time.Since(start)
+}
@@ -0,0 +1,57 @@
+package network
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "first ")
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "second")
+	}))
+	defer server.Close()
+
+	cfg := NewConfig(5 * time.Second)
+	cfg.LoggingConfig.LogTimings = true
+	initTestConfig(t, cfg)
+
+	var first, second *Response
+	output := captureOutput(t, func() {
+		var err error
+		if first, err = MakeRequestForResponse(context.Background(), "GET", "Get Report", server.URL, nil, nil); err != nil {
+			t.Errorf("first request failed: %v", err)
+		}
+		if second, err = MakeRequestForResponse(context.Background(), "GET", "Get Report", server.URL, nil, nil); err != nil {
+			t.Errorf("second request failed: %v", err)
+		}
+	})
+	if first == nil || second == nil {
+		t.FailNow()
+	}
+
+	if first.Timings.ConnectionReused || first.Timings.Connect <= 0 {
+		t.Fatalf("first timings = %s, want a new connection", first.Timings)
+	}
+	if first.Timings.TimeToFirstByte < 20*time.Millisecond {
+		t.Fatalf("time to first byte = %s, want at least the server's 20ms delay", first.Timings.TimeToFirstByte)
+	}
+	if first.Timings.BodyTransfer < 15*time.Millisecond {
+		t.Fatalf("body transfer = %s, want about the 20ms pause between chunks", first.Timings.BodyTransfer)
+	}
+	if !second.Timings.ConnectionReused || second.Timings.Connect != 0 {
+		t.Fatalf("second timings = %s, want the first connection reused", second.Timings)
+	}
+
+	if !strings.Contains(output, "[timings] dns=") || !strings.Contains(output, "reused=true") {
+		t.Fatalf("timings were not logged:\n%s", output)
+	}
+}
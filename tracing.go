@@ -0,0 +1,135 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Tracer creates spans for outbound calls. The otelnetwork package implements it with
+// OpenTelemetry; other tracing libraries can be plugged in the same way.
+type Tracer interface {
+	// Start starts a client span as a child of the span in ctx and returns a context carrying it
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+	// Inject writes the trace context of ctx into the outgoing headers, e.g. a W3C traceparent
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a single traced operation
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	AddEvent(name string, attributes ...Attribute)
+	SetError(err error) // Records err and marks the span as failed
+	End()
+}
+
+// Attribute is a key-value pair attached to spans and span events
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attribute keys, following the OpenTelemetry HTTP semantic conventions where one exists
+const (
+	AttrHTTPMethod         = "http.request.method"
+	AttrURLFull            = "url.full"
+	AttrServerAddress      = "server.address"
+	AttrHTTPStatusCode     = "http.response.status_code"
+	AttrHTTPResendCount    = "http.request.resend_count"
+	AttrErrorType          = "error.type"
+	AttrRequestDescription = "request.description"
+)
+
+// Span event names
+const (
+	EventRetry       = "retry"
+	EventCircuitOpen = "circuit_breaker.rejected"
+)
+
+// noopSpan is used when no Tracer is configured
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute)    {}
+func (noopSpan) AddEvent(string, ...Attribute) {}
+func (noopSpan) SetError(error)                {}
+func (noopSpan) End()                          {}
+
+type spanContextKey struct{}
+
+// startSpan starts a span with the configured Tracer, or returns a no-op span when tracing is disabled
+func startSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	if config.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	ctx, span := config.Tracer.Start(ctx, name, attributes...)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// spanFromContext returns the innermost span started by this package, or a no-op span
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// injectTraceContext propagates the current trace into the request headers
+func injectTraceContext(ctx context.Context, header http.Header) {
+	if config.Tracer != nil {
+		config.Tracer.Inject(ctx, header)
+	}
+}
+
+// spanAttributes returns the attributes shared by the call span and its attempt spans
+func (s *requestSpec) spanAttributes() []Attribute {
+	attributes := []Attribute{
+		{Key: AttrHTTPMethod, Value: s.method},
+		{Key: AttrRequestDescription, Value: s.description},
+	}
+
+	// Never put credentials from the URL into traces
+	if u, err := url.Parse(s.url); err == nil {
+		attributes = append(attributes,
+			Attribute{Key: AttrURLFull, Value: u.Redacted()},
+			Attribute{Key: AttrServerAddress, Value: u.Hostname()},
+		)
+	}
+	return attributes
+}
+
+// endSpan records the outcome of a call or attempt and ends its span
+func endSpan(span Span, resp *Response, err error) {
+	if resp != nil {
+		span.SetAttributes(Attribute{Key: AttrHTTPStatusCode, Value: resp.StatusCode})
+	}
+	if err != nil {
+		span.SetAttributes(Attribute{Key: AttrErrorType, Value: errorType(resp, err)})
+		span.SetError(err)
+	}
+	span.End()
+}
+
+// errorType classifies a failure into a low-cardinality value
+func errorType(resp *Response, err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case resp != nil && resp.StatusCode >= 400:
+		return strconv.Itoa(resp.StatusCode)
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrConcurrencyLimited):
+		return "concurrency_limited"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "_OTHER"
+	}
+}
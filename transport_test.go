@@ -0,0 +1,97 @@
+package network
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport forwards to the default transport, recording what it is given
+type countingTransport struct {
+	requests      atomic.Int32
+	authorization atomic.Value
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	c.authorization.Store(req.Header.Get("Authorization"))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCustomTransportKeepsPipeline(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	cfg := NewConfig(5 * time.Second).
+		WithTransport(transport).
+		WithAuthenticator(BearerAuth{Token: "secret"})
+	cfg.RetryConfig.MaxRetries = 1
+	cfg.RetryConfig.RetryDelay = time.Millisecond
+	initTestConfig(t, cfg)
+
+	var err error
+	output := captureOutput(t, func() {
+		_, err = MakeGETRequest("Get Users Through Proxy", server.URL, nil, nil)
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// Retries, credentials and logging all happen on top of the injected transport
+	if got := transport.requests.Load(); got != 2 {
+		t.Fatalf("transport saw %d requests, want the first attempt and its retry", got)
+	}
+	if got := transport.authorization.Load(); got != "Bearer secret" {
+		t.Fatalf("transport got Authorization %v, want the bearer token", got)
+	}
+	if !strings.Contains(output, "Get Users Through Proxy") {
+		t.Fatalf("the request was not logged:\n%s", output)
+	}
+}
+
+func TestCustomHTTPClientAndDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	initTestConfig(t, newTestConfig().WithHTTPClient(&http.Client{Transport: transport}))
+	if _, err := MakeGETRequest("Get Users", server.URL, nil, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if transport.requests.Load() != 1 {
+		t.Fatal("the custom client was not used")
+	}
+
+	var dials atomic.Int32
+	dialer := &net.Dialer{}
+	initTestConfig(t, newTestConfig().WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return dialer.DialContext(ctx, network, addr)
+	}))
+	if _, err := MakeGETRequest("Get Users", server.URL, nil, nil); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if dials.Load() != 1 {
+		t.Fatalf("custom dialer used %d times, want 1", dials.Load())
+	}
+}
+
+func TestOnlyOneTransportOverride(t *testing.T) {
+	cfg := newTestConfig().
+		WithHTTPClient(&http.Client{}).
+		WithTransport(http.DefaultTransport)
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected setting both httpClient and transport to be rejected")
+	}
+}
@@ -0,0 +1,83 @@
+package network
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// XML content types
+const (
+	contentTypeXML = "application/xml; charset=utf-8"
+	acceptXML      = "application/xml, text/xml"
+)
+
+// EncodeXML marshals v with encoding/xml, prefixed with the standard XML header
+func EncodeXML(v interface{}) (string, error) {
+	encoded, err := xml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode XML: %w", err)
+	}
+	return xml.Header + string(encoded), nil
+}
+
+// DecodeXML unmarshals an XML document into a value of type T
+func DecodeXML[T any](body string) (T, error) {
+	var result T
+	if strings.TrimSpace(body) == "" {
+		return result, nil
+	}
+	if err := xml.Unmarshal([]byte(body), &result); err != nil {
+		return result, fmt.Errorf("failed to decode XML: %w", err)
+	}
+	return result, nil
+}
+
+// withXMLHeaders copies headers and adds XML Content-Type and Accept headers unless already set
+func withXMLHeaders(headers map[string]string, hasBody bool) map[string]string {
+	result := make(map[string]string, len(headers)+2)
+	hasContentType, hasAccept := false, false
+	for key, value := range headers {
+		result[key] = value
+		hasContentType = hasContentType || strings.EqualFold(key, "Content-Type")
+		hasAccept = hasAccept || strings.EqualFold(key, "Accept")
+	}
+
+	if hasBody && !hasContentType {
+		result["Content-Type"] = contentTypeXML
+	}
+	if !hasAccept {
+		result["Accept"] = acceptXML
+	}
+	return result
+}
+
+// MakeXMLRequest sends payload encoded with encoding/xml using any HTTP method and
+// decodes the XML response into T. A nil payload sends no body, which is the usual
+// choice for GET, DELETE, HEAD and OPTIONS. The payload is encoded as XML whatever
+// Content-Type is declared, e.g. a vendor type. Responses without a Content-Type, or
+// labelled with one that has no codec such as text/plain, are decoded as XML.
+func MakeXMLRequest[T any](method, description, urlStr string, payload interface{}, headers map[string]string) (T, error) {
+	return makeTypedRequest[T](context.Background(), method, description, urlStr, payload, withXMLHeaders(headers, payload != nil), xmlCodec{})
+}
+
+// MakeXMLGETRequest sends a GET request with query parameters and decodes the XML response into T
+func MakeXMLGETRequest[T any](description, baseURL string, queryParams map[string]string, headers map[string]string) (T, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		var result T
+		return result, err
+	}
+
+	if len(queryParams) > 0 {
+		q := u.Query()
+		for key, value := range queryParams {
+			q.Set(key, value)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return MakeXMLRequest[T](methodGET, description, u.String(), nil, headers)
+}
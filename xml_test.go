@@ -0,0 +1,47 @@
+package network
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type xmlTestUser struct {
+	XMLName xml.Name `xml:"user"`
+	Name    string   `xml:"name"`
+}
+
+func TestXMLRequestDecodesUnlabelledResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType []string
+	}{
+		{name: "no content type", contentType: nil},
+		{name: "text/plain", contentType: []string{"text/plain; charset=utf-8"}},
+		{name: "text/html", contentType: []string{"text/html"}},
+		{name: "application/xml", contentType: []string{"application/xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// A nil value stops the server from sniffing a Content-Type
+				w.Header()["Content-Type"] = tt.contentType
+				io.WriteString(w, `<?xml version="1.0"?><user><name>Ada</name></user>`)
+			}))
+			defer server.Close()
+
+			initTestConfig(t, newTestConfig())
+
+			user, err := MakeXMLGETRequest[xmlTestUser]("Get User", server.URL, nil, nil)
+			if err != nil {
+				t.Fatalf("MakeXMLGETRequest: %v", err)
+			}
+			if user.Name != "Ada" {
+				t.Fatalf("expected name Ada, got %q", user.Name)
+			}
+		})
+	}
+}